@@ -0,0 +1,107 @@
+// Command chirpy-keys manages the JWT signing keys in JWT_KEYS_DIR.
+//
+// A rotation is three steps, with a deploy after each so that every server
+// already trusts a key before any server signs with it:
+//
+//	chirpy-keys generate            # prints the new kid
+//	chirpy-keys activate <kid>
+//	chirpy-keys remove <old kid>    # once tokens signed by it have expired
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+)
+
+const usage = `usage: chirpy-keys [-dir DIR] <command> [args]
+
+commands:
+  list                       show keys, marking the current one with *
+  generate [-alg ALG] [-activate]
+                             add a new key (ALG is EdDSA or RS256)
+  activate <kid>             sign new tokens with <kid>
+  remove <kid>               delete a key that is no longer current
+`
+
+func main() {
+	log.SetFlags(0)
+	flag.Usage = func() { fmt.Fprint(flag.CommandLine.Output(), usage) }
+	dirFlag := flag.String("dir", os.Getenv("JWT_KEYS_DIR"), "key directory (default $JWT_KEYS_DIR)")
+	flag.Parse()
+
+	if *dirFlag == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := auth.KeyDir(*dirFlag)
+	args := flag.Args()[1:]
+
+	var err error
+	switch flag.Arg(0) {
+	case "list":
+		err = list(dir)
+	case "generate":
+		err = generate(dir, args)
+	case "activate":
+		if len(args) != 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		err = dir.SetCurrent(args[0])
+	case "remove":
+		if len(args) != 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		err = dir.Remove(args[0])
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func list(dir auth.KeyDir) error {
+	keys, current, err := dir.Keys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		marker := " "
+		if key.ID == current {
+			marker = "*"
+		}
+		fmt.Printf("%s %-6s %s\n", marker, key.Algorithm(), key.ID)
+	}
+	return nil
+}
+
+func generate(dir auth.KeyDir, args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	alg := fs.String("alg", auth.AlgEdDSA, "signing algorithm, EdDSA or RS256")
+	activate := fs.Bool("activate", false, "make the new key current immediately")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	key, err := auth.GenerateKey(*alg)
+	if err != nil {
+		return err
+	}
+	if err = dir.Add(key); err != nil {
+		return err
+	}
+	if *activate {
+		if err = dir.SetCurrent(key.ID); err != nil {
+			return err
+		}
+	}
+	fmt.Println(key.ID)
+	return nil
+}
@@ -2,17 +2,25 @@ package main
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/Myles-J/chirpy/internal/api"
+	"github.com/Myles-J/chirpy/internal/auth"
 	"github.com/Myles-J/chirpy/internal/config"
 	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/mail"
+	"github.com/Myles-J/chirpy/internal/password"
+	"github.com/Myles-J/chirpy/internal/throttle"
 	"github.com/Myles-J/chirpy/internal/utils"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
 )
 
 const readHeaderTimeout = 5 * time.Second
@@ -28,8 +36,68 @@ func main() {
 	// Get required environment variables
 	dbURL := utils.MustGetenv("DB_URL")
 	platform := utils.MustGetenv("PLATFORM")
-	jwtSecret := utils.MustGetenv("JWT_SECRET")
+	jwtSecret := os.Getenv("JWT_SECRET")
 	polkaSecret := utils.MustGetenv("POLKA_SECRET")
+	adminAPIKey := os.Getenv("ADMIN_API_KEY")
+	baseURL := utils.GetenvDefault("BASE_URL", "http://localhost:"+port)
+
+	requireVerifiedEmail, parseErr := strconv.ParseBool(utils.GetenvDefault("REQUIRE_EMAIL_VERIFICATION", "false"))
+	if parseErr != nil {
+		log.Fatal("Invalid REQUIRE_EMAIL_VERIFICATION:", parseErr)
+	}
+
+	// Login throttling: after LOGIN_FREE_ATTEMPTS failures each further one
+	// doubles the wait before the next attempt, from LOGIN_BACKOFF_BASE up to
+	// LOGIN_BACKOFF_MAX. At LOGIN_MAX_FAILURES the account is locked for
+	// LOGIN_LOCKOUT_DURATION. Client addresses get looser LOGIN_IP_ limits,
+	// since many users can share one.
+	accountPolicy, ipPolicy, throttleErr := loginPolicies()
+	if throttleErr != nil {
+		log.Fatal("Invalid login throttling setting:", throttleErr)
+	}
+
+	hasher, hasherErr := passwordHasher()
+	if hasherErr != nil {
+		log.Fatal("Invalid password hashing setting:", hasherErr)
+	}
+
+	passwordPolicy, policyErr := loadPasswordPolicy()
+	if policyErr != nil {
+		log.Fatal("Invalid password policy setting:", policyErr)
+	}
+
+	// JWT keys: with JWT_KEYS_DIR set, tokens are signed with the directory's
+	// current key and JWT_SECRET only verifies tokens issued before startup,
+	// for as long as an access token lives. Without it, JWT_SECRET signs
+	// HS256 tokens as before.
+	var keys *auth.Keyring
+	if keysDir := os.Getenv("JWT_KEYS_DIR"); keysDir != "" {
+		var keysErr error
+		keys, keysErr = auth.KeyDir(keysDir).Load()
+		if keysErr != nil {
+			log.Fatal("Error loading JWT keys:", keysErr)
+		}
+		keys.AcceptLegacySecret(jwtSecret, api.AccessTokenTTL)
+	} else {
+		keys = auth.NewHMACKeyring(utils.MustGetenv("JWT_SECRET"))
+	}
+
+	// Mail setup: "log" prints emails to stdout for development.
+	var mailer mail.Mailer
+	mailFrom := utils.GetenvDefault("MAIL_FROM", "no-reply@localhost")
+	switch mailerKind := utils.GetenvDefault("MAILER", "log"); mailerKind {
+	case "smtp":
+		mailer = mail.NewSMTPMailer(
+			utils.MustGetenv("SMTP_ADDR"),
+			mailFrom,
+			os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"),
+		)
+	case "log":
+		mailer = mail.NewLogMailer(mailFrom, os.Stdout)
+	default:
+		log.Fatalf("Unknown MAILER %q, expected smtp or log", mailerKind)
+	}
 
 	// Database setup
 	dbConn, dbOpenErr := sql.Open("postgres", dbURL)
@@ -39,6 +107,25 @@ func main() {
 	defer dbConn.Close()
 
 	dbQueries := database.New(dbConn)
+
+	// Revoked access tokens: "memory" only suits a single server instance.
+	var denylist auth.Denylist
+	switch denylistKind := utils.GetenvDefault("DENYLIST", "postgres"); denylistKind {
+	case "postgres":
+		denylist = database.NewDenylist(dbQueries)
+	case "memory":
+		denylist = auth.NewMemoryDenylist(api.AccessTokenTTL)
+	default:
+		log.Fatalf("Unknown DENYLIST %q, expected postgres or memory", denylistKind)
+	}
+	keys.UseDenylist(denylist)
+	keys.UsePersonalTokens(database.NewPersonalTokens(dbQueries))
+
+	loginThrottle := api.NewLoginThrottle(dbConn, dbQueries, accountPolicy, ipPolicy)
+	deviceThrottle := api.NewRequestThrottle(dbConn, dbQueries, "device", deviceCodePolicy())
+	resetThrottle := api.NewRequestThrottle(dbConn, dbQueries, "password_reset", passwordResetPolicy())
+	verificationThrottle := api.NewRequestThrottle(dbConn, dbQueries, "verification", verificationEmailPolicy())
+	verifier := api.NewEmailVerifier(dbQueries, verificationThrottle, mailer, baseURL)
 	apiCfg := config.NewAPIConfig(dbQueries, platform, jwtSecret, polkaSecret)
 
 	// Create a new ServeMux
@@ -53,27 +140,83 @@ func main() {
 		apiCfg.HandlerMetrics(http.StripPrefix("/app/assets/", http.FileServer(http.Dir("assets")))),
 	)
 
+	deviceVerification := api.DeviceVerificationHandler(dbQueries, loginThrottle, hasher)
+	mux.HandleFunc("GET /app/device", deviceVerification)
+	mux.HandleFunc("POST /app/device", deviceVerification)
+	verifyEmailPage := api.VerifyEmailPageHandler(dbConn, dbQueries)
+	mux.HandleFunc("GET /app/verify", verifyEmailPage)
+	mux.HandleFunc("POST /app/verify", verifyEmailPage)
+	resetPasswordPage := api.ResetPasswordPageHandler(dbConn, dbQueries, denylist, hasher, passwordPolicy)
+	mux.HandleFunc("GET /app/reset-password", resetPasswordPage)
+	mux.HandleFunc("POST /app/reset-password", resetPasswordPage)
+
 	// --- Health Check Endpoint ---
 	mux.HandleFunc("GET /api/healthz", api.HandleHealthCheck)
 
 	// --- Admin Endpoints ---
 	mux.HandleFunc("GET /admin/metrics", apiCfg.MetricsHandler)
 	mux.HandleFunc("POST /admin/reset", apiCfg.ResetHandler)
+	mux.HandleFunc("POST /admin/users/{id}/suspend", api.SuspendUserHandler(dbQueries, denylist, adminAPIKey))
+	mux.HandleFunc("POST /admin/users/{id}/unsuspend", api.UnsuspendUserHandler(dbQueries, adminAPIKey))
 
 	// --- Authentication Endpoints ---
-	mux.HandleFunc("POST /api/login", api.LoginHandler(dbQueries, jwtSecret))
-	mux.HandleFunc("POST /api/refresh", api.RefreshHandler(dbQueries, jwtSecret))
-	mux.HandleFunc("POST /api/revoke", api.RevokeHandler(dbQueries))
+	mux.HandleFunc("GET /.well-known/jwks.json", api.JWKSHandler(keys))
+	mux.HandleFunc("POST /api/login", api.LoginHandler(dbQueries, keys, loginThrottle, hasher))
+	mux.HandleFunc("POST /api/login/2fa", api.LoginTwoFactorHandler(dbQueries, keys, denylist, loginThrottle))
+	mux.HandleFunc("POST /api/2fa/setup", api.TwoFactorSetupHandler(dbQueries, keys))
+	mux.HandleFunc("POST /api/2fa/confirm", api.TwoFactorConfirmHandler(dbConn, dbQueries, keys))
+	mux.HandleFunc("POST /api/refresh", api.RefreshHandler(dbConn, dbQueries, keys))
+	mux.HandleFunc("POST /api/revoke", api.RevokeHandler(dbQueries, keys, denylist))
+	mux.HandleFunc("GET /api/sessions", api.ListSessionsHandler(dbQueries, keys))
+	mux.HandleFunc("DELETE /api/sessions/{id}", api.RevokeSessionHandler(dbQueries, keys))
+	mux.HandleFunc("POST /api/sessions/revoke-all", api.RevokeAllSessionsHandler(dbQueries, keys, denylist))
+	mux.HandleFunc("POST /api/tokens", api.CreatePersonalTokenHandler(dbQueries, keys))
+	mux.HandleFunc("GET /api/tokens", api.ListPersonalTokensHandler(dbQueries, keys))
+	mux.HandleFunc("DELETE /api/tokens/{id}", api.RevokePersonalTokenHandler(dbQueries, keys))
+	mux.HandleFunc("POST /api/password/forgot", api.ForgotPasswordHandler(dbQueries, resetThrottle, mailer, baseURL))
+	mux.HandleFunc("POST /api/password/reset", api.ResetPasswordHandler(dbConn, dbQueries, denylist, hasher, passwordPolicy))
+
+	// --- OAuth Endpoints ---
+	oauthAuthorize := api.OAuthAuthorizeHandler(dbQueries, loginThrottle, hasher)
+	mux.HandleFunc("GET /.well-known/oauth-authorization-server", api.OAuthMetadataHandler(baseURL))
+	mux.HandleFunc("GET /oauth/authorize", oauthAuthorize)
+	mux.HandleFunc("POST /oauth/authorize", oauthAuthorize)
+	mux.HandleFunc("POST /oauth/token", api.OAuthTokenHandler(dbConn, dbQueries, keys))
+	mux.HandleFunc("POST /oauth/device/code", api.DeviceCodeHandler(dbQueries, deviceThrottle, baseURL))
+	mux.HandleFunc("POST /api/oauth/clients", api.CreateOAuthClientHandler(dbQueries, keys))
+	mux.HandleFunc("GET /api/oauth/clients", api.ListOAuthClientsHandler(dbQueries, keys))
+	mux.HandleFunc("DELETE /api/oauth/clients/{id}", api.DeleteOAuthClientHandler(dbQueries, keys))
 
 	// --- User Endpoints ---
-	mux.HandleFunc("POST /api/users", api.CreateUserHandler(dbQueries))
-	mux.HandleFunc("PUT /api/users", api.UpdateUserHandler(dbQueries, jwtSecret))
+	mux.HandleFunc("POST /api/users", api.CreateUserHandler(dbQueries, verifier, hasher, passwordPolicy))
+	mux.HandleFunc("PUT /api/users", api.UpdateUserHandler(dbQueries, verifier, keys, denylist, hasher, passwordPolicy))
+	mux.HandleFunc("POST /api/users/verify", api.VerifyEmailHandler(dbConn, dbQueries))
+	mux.HandleFunc("POST /api/users/verify/resend", api.ResendVerificationHandler(dbQueries, verifier, keys))
+	mux.HandleFunc("GET /api/users/{username}", api.GetProfileHandler(dbQueries))
+
+	// --- Follow Endpoints ---
+	mux.HandleFunc("POST /api/users/{id}/follow", api.FollowUserHandler(dbQueries, keys))
+	mux.HandleFunc("DELETE /api/users/{id}/follow", api.UnfollowUserHandler(dbQueries, keys))
+	mux.HandleFunc("GET /api/users/{id}/followers", api.ListFollowersHandler(dbQueries))
+	mux.HandleFunc("GET /api/users/{id}/following", api.ListFollowingHandler(dbQueries))
+	mux.HandleFunc("GET /api/users/me/mentions", api.ListMyMentionsHandler(dbQueries, keys))
+	mux.HandleFunc("GET /api/timeline", api.TimelineHandler(dbQueries, keys))
 
 	// --- Chirp Endpoints ---
-	mux.HandleFunc("POST /api/chirps", api.CreateChirpHandler(dbQueries, jwtSecret))
-	mux.HandleFunc("DELETE /api/chirps/{id}", api.DeleteChirpHandler(dbQueries, jwtSecret))
-	mux.HandleFunc("GET /api/chirps", api.ListChirpsHandler(dbQueries))
-	mux.HandleFunc("GET /api/chirps/{id}", api.GetChirpHandler(dbQueries))
+	mux.HandleFunc("POST /api/chirps", api.CreateChirpHandler(dbConn, dbQueries, keys, requireVerifiedEmail))
+	mux.HandleFunc("DELETE /api/chirps/{id}", api.DeleteChirpHandler(dbQueries, keys))
+	mux.HandleFunc("GET /api/chirps", api.ListChirpsHandler(dbQueries, keys))
+	mux.HandleFunc("GET /api/chirps/{id}", api.GetChirpHandler(dbQueries, keys))
+	mux.HandleFunc("PUT /api/chirps/{id}", api.UpdateChirpHandler(dbConn, dbQueries, keys))
+	mux.HandleFunc("GET /api/chirps/{id}/revisions", api.ListChirpRevisionsHandler(dbQueries))
+	mux.HandleFunc("GET /api/chirps/search", api.SearchChirpsHandler(dbQueries, keys))
+	mux.HandleFunc("GET /api/chirps/{id}/thread", api.GetThreadHandler(dbQueries, keys))
+	mux.HandleFunc("POST /api/chirps/{id}/likes", api.LikeChirpHandler(dbConn, dbQueries, keys))
+	mux.HandleFunc("DELETE /api/chirps/{id}/likes", api.UnlikeChirpHandler(dbConn, dbQueries, keys))
+	mux.HandleFunc("DELETE /api/chirps/{id}/rechirps", api.DeleteRechirpHandler(dbQueries, keys))
+
+	mux.HandleFunc("GET /api/hashtags/trending", api.TrendingHashtagsHandler(dbQueries))
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", api.ListHashtagChirpsHandler(dbQueries, keys))
 
 	// ---- Polka Endpoint ----
 	mux.HandleFunc("POST /api/polka/webhooks", api.PolkaWebhookHandler(dbQueries, polkaSecret))
@@ -90,3 +233,128 @@ func main() {
 		log.Printf("Server error: %v", err)
 	}
 }
+
+// loginPolicies reads the per-account and per-address login throttling
+// policies from the environment.
+func loginPolicies() (throttle.Policy, throttle.Policy, error) {
+	var account, ip throttle.Policy
+	var err error
+	settings := []struct {
+		dst      *int
+		key      string
+		fallback int
+	}{
+		{&account.FreeAttempts, "LOGIN_FREE_ATTEMPTS", 3},
+		{&account.LockoutThreshold, "LOGIN_MAX_FAILURES", 10},
+		{&ip.FreeAttempts, "LOGIN_IP_FREE_ATTEMPTS", 20},
+		{&ip.LockoutThreshold, "LOGIN_IP_MAX_FAILURES", 100},
+	}
+	for _, s := range settings {
+		if *s.dst, err = utils.GetenvInt(s.key, s.fallback); err != nil {
+			return account, ip, err
+		}
+	}
+	if account.BaseDelay, err = utils.GetenvDuration("LOGIN_BACKOFF_BASE", time.Second); err != nil {
+		return account, ip, err
+	}
+	if account.MaxDelay, err = utils.GetenvDuration("LOGIN_BACKOFF_MAX", time.Minute); err != nil {
+		return account, ip, err
+	}
+	if account.Lockout, err = utils.GetenvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute); err != nil {
+		return account, ip, err
+	}
+	ip.BaseDelay, ip.MaxDelay, ip.Lockout = account.BaseDelay, account.MaxDelay, account.Lockout
+	return account, ip, nil
+}
+
+// deviceCodePolicy limits how often one client address may start device
+// logins: ten freely, then with a growing wait before each one until it
+// has made none for ten minutes.
+func deviceCodePolicy() throttle.Policy {
+	return throttle.Policy{
+		FreeAttempts: 10,
+		BaseDelay:    10 * time.Second,
+		MaxDelay:     5 * time.Minute,
+		Lockout:      10 * time.Minute,
+	}
+}
+
+// passwordResetPolicy limits how many password reset emails one client
+// address may ask for, or one address may be sent: a few freely, then with
+// a growing wait between them until none has been asked for in an hour.
+func passwordResetPolicy() throttle.Policy {
+	return throttle.Policy{
+		FreeAttempts: 3,
+		BaseDelay:    time.Minute,
+		MaxDelay:     15 * time.Minute,
+		Lockout:      time.Hour,
+	}
+}
+
+// verificationEmailPolicy limits how many verification emails go to one
+// user or address: a few freely, then with a wait growing from a minute to
+// an hour between them until none has been sent for a day.
+func verificationEmailPolicy() throttle.Policy {
+	return throttle.Policy{
+		FreeAttempts: 3,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Hour,
+		Lockout:      24 * time.Hour,
+	}
+}
+
+// passwordHasher builds the hasher for new passwords from the environment.
+// PASSWORD_HASHER is "argon2id" (the default), tuned with ARGON2_MEMORY in
+// KiB, ARGON2_ITERATIONS and ARGON2_PARALLELISM, or "bcrypt", tuned with
+// BCRYPT_COST. Existing hashes in the other format are upgraded at login.
+func passwordHasher() (auth.PasswordHasher, error) {
+	switch kind := utils.GetenvDefault("PASSWORD_HASHER", "argon2id"); kind {
+	case "argon2id":
+		memory, err := utils.GetenvInt("ARGON2_MEMORY", auth.Argon2idMemory)
+		if err != nil {
+			return nil, err
+		}
+		iterations, err := utils.GetenvInt("ARGON2_ITERATIONS", auth.Argon2idIterations)
+		if err != nil {
+			return nil, err
+		}
+		parallelism, err := utils.GetenvInt("ARGON2_PARALLELISM", auth.Argon2idParallelism)
+		if err != nil {
+			return nil, err
+		}
+		return auth.NewArgon2idHasher(memory, iterations, parallelism)
+	case "bcrypt":
+		cost, err := utils.GetenvInt("BCRYPT_COST", bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		return auth.NewBcryptHasher(cost)
+	default:
+		return nil, fmt.Errorf("unknown PASSWORD_HASHER %q, expected argon2id or bcrypt", kind)
+	}
+}
+
+// loadPasswordPolicy reads the rules for new passwords from the environment:
+// PASSWORD_MIN_LENGTH, PASSWORD_MAX_LENGTH, PASSWORD_MIN_SCORE (0 to 4, see
+// password.Estimate) and BREACHED_PASSWORDS_FILE, an optional list of
+// SHA-1 hashes of breached passwords loaded once at startup.
+func loadPasswordPolicy() (password.Policy, error) {
+	var policy password.Policy
+	var err error
+	if policy.MinLength, err = utils.GetenvInt("PASSWORD_MIN_LENGTH", 8); err != nil {
+		return policy, err
+	}
+	if policy.MaxLength, err = utils.GetenvInt("PASSWORD_MAX_LENGTH", 128); err != nil {
+		return policy, err
+	}
+	if policy.MinScore, err = utils.GetenvInt("PASSWORD_MIN_SCORE", 3); err != nil {
+		return policy, err
+	}
+	if path := os.Getenv("BREACHED_PASSWORDS_FILE"); path != "" {
+		if policy.Breached, err = password.OpenCorpus(path); err != nil {
+			return policy, err
+		}
+		log.Printf("Loaded %d breached passwords from %s", policy.Breached.Len(), path)
+	}
+	return policy, nil
+}
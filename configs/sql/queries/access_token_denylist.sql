@@ -0,0 +1,21 @@
+-- name: RevokeAccessToken :exec
+INSERT INTO revoked_access_tokens (jti, expires_at)
+VALUES ($1, $2)
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: DeleteExpiredRevokedAccessTokens :exec
+DELETE FROM revoked_access_tokens WHERE expires_at < NOW();
+
+-- name: SetAccessTokenCutoff :exec
+INSERT INTO access_token_cutoffs (user_id, not_before)
+VALUES ($1, date_trunc('second', sqlc.arg('not_before')::timestamp))
+ON CONFLICT (user_id) DO UPDATE
+SET not_before = GREATEST(access_token_cutoffs.not_before, EXCLUDED.not_before);
+
+-- name: IsAccessTokenRevoked :one
+SELECT
+    EXISTS (SELECT 1 FROM revoked_access_tokens WHERE jti = sqlc.arg('jti'))
+    OR EXISTS (
+        SELECT 1 FROM access_token_cutoffs
+        WHERE user_id = sqlc.arg('user_id') AND not_before > sqlc.arg('issued_at')
+    ) AS revoked;
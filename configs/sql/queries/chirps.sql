@@ -1,16 +1,107 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, rechirp_of, quote_of)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5)
 RETURNING *;
 
--- name: ListChirps :many
-SELECT * from chirps order by created_at ASC;
+-- name: ListChirpsAsc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_limit');
 
--- name: ListChirpsByAuthor :many
-SELECT * from chirps where user_id = $1 order by created_at ASC;
+-- name: ListChirpsDesc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit');
 
 -- name: GetChirp :one
 SELECT * from chirps where id = $1 LIMIT 1;
 
+-- name: GetChirpForUpdate :one
+SELECT * FROM chirps WHERE id = $1 FOR UPDATE;
+
+-- name: ListChirpsByIDs :many
+SELECT * FROM chirps WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
 -- name: DeleteChirp :exec
-DELETE FROM chirps WHERE id = $1 AND user_id = $2;
\ No newline at end of file
+DELETE FROM chirps WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteRechirp :execrows
+DELETE FROM chirps WHERE user_id = $1 AND rechirp_of = $2;
+
+-- name: TombstoneChirp :exec
+WITH purged_revisions AS (
+    DELETE FROM chirp_revisions
+    WHERE chirp_id IN (SELECT id FROM chirps WHERE id = $1 AND user_id = $2)
+), purged_hashtags AS (
+    DELETE FROM chirp_hashtags
+    WHERE chirp_id IN (SELECT id FROM chirps WHERE id = $1 AND user_id = $2)
+), purged_mentions AS (
+    DELETE FROM chirp_mentions
+    WHERE chirp_id IN (SELECT id FROM chirps WHERE id = $1 AND user_id = $2)
+)
+UPDATE chirps
+SET body = '', deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND user_id = $2;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $1, edited_at = NOW(), updated_at = NOW()
+WHERE id = $2
+RETURNING *;
+
+-- name: ListTimeline :many
+SELECT chirps.* FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = sqlc.arg('user_id')
+  AND chirps.deleted_at IS NULL
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: ListChirpAncestors :many
+WITH RECURSIVE ancestors (id, in_reply_to, depth) AS (
+    SELECT c.id, c.in_reply_to, 0 FROM chirps c WHERE c.id = $1
+    UNION ALL
+    SELECT c.id, c.in_reply_to, a.depth + 1
+    FROM chirps c
+    JOIN ancestors a ON c.id = a.in_reply_to
+)
+SELECT chirps.* FROM chirps
+JOIN ancestors ON ancestors.id = chirps.id
+WHERE ancestors.depth > 0
+ORDER BY ancestors.depth DESC;
+
+-- name: ListChirpDescendants :many
+WITH RECURSIVE descendants (id, depth) AS (
+    SELECT c.id, 1 FROM chirps c WHERE c.in_reply_to = sqlc.arg('chirp_id')
+    UNION ALL
+    SELECT c.id, d.depth + 1
+    FROM chirps c
+    JOIN descendants d ON c.in_reply_to = d.id
+)
+SELECT sqlc.embed(chirps), descendants.depth::int AS depth
+FROM chirps
+JOIN descendants ON descendants.id = chirps.id
+ORDER BY descendants.depth, chirps.created_at, chirps.id
+LIMIT sqlc.arg('page_limit') OFFSET sqlc.arg('page_offset');
+
+-- name: AdjustChirpLikeCount :one
+UPDATE chirps
+SET like_count = like_count + sqlc.arg('delta')
+WHERE id = sqlc.arg('id')
+RETURNING like_count;
@@ -0,0 +1,23 @@
+-- name: CreateDeviceAuthorization :exec
+INSERT INTO device_authorizations (device_code_hash, user_code, created_at, expires_at, poll_interval)
+VALUES ($1, $2, NOW(), $3, $4);
+
+-- name: GetDeviceAuthorizationForUpdate :one
+SELECT * FROM device_authorizations WHERE device_code_hash = $1 FOR UPDATE;
+
+-- name: RecordDevicePoll :exec
+UPDATE device_authorizations SET last_polled_at = $1, poll_interval = $2 WHERE device_code_hash = $3;
+
+-- name: ApproveDeviceAuthorization :execrows
+UPDATE device_authorizations SET user_id = $1, approved_at = NOW()
+WHERE user_code = $2 AND expires_at > NOW() AND approved_at IS NULL AND denied_at IS NULL;
+
+-- name: DenyDeviceAuthorization :execrows
+UPDATE device_authorizations SET denied_at = NOW()
+WHERE user_code = $1 AND expires_at > NOW() AND approved_at IS NULL AND denied_at IS NULL;
+
+-- name: DeleteDeviceAuthorization :exec
+DELETE FROM device_authorizations WHERE device_code_hash = $1;
+
+-- name: DeleteExpiredDeviceAuthorizations :exec
+DELETE FROM device_authorizations WHERE expires_at < NOW();
@@ -0,0 +1,31 @@
+-- name: EnsureLoginThrottles :exec
+INSERT INTO login_throttles (key, failures, last_failure_at)
+SELECT unnest(sqlc.arg('keys')::text[]), 0, sqlc.arg('created_at')::timestamp
+ON CONFLICT (key) DO NOTHING;
+
+-- name: LockLoginThrottles :many
+SELECT * FROM login_throttles
+WHERE key = ANY(sqlc.arg('keys')::text[])
+ORDER BY key
+FOR UPDATE;
+
+-- name: RecordLoginFailure :exec
+INSERT INTO login_throttles (key, failures, last_failure_at)
+VALUES (sqlc.arg('key'), 1, sqlc.arg('failed_at'))
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+        WHEN login_throttles.last_failure_at < sqlc.arg('forget_before') THEN 1
+        ELSE login_throttles.failures + 1
+    END,
+    last_failure_at = EXCLUDED.last_failure_at;
+
+-- name: ReleaseLoginAttempt :exec
+UPDATE login_throttles SET failures = failures - 1
+WHERE key = ANY(sqlc.arg('keys')::text[]) AND failures > 0;
+
+-- name: ClearLoginFailures :exec
+DELETE FROM login_throttles WHERE key = $1;
+
+-- name: DeleteStaleLoginThrottles :exec
+DELETE FROM login_throttles
+WHERE key LIKE ANY(sqlc.arg('key_patterns')::text[]) AND last_failure_at < sqlc.arg('forget_before');
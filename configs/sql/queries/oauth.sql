@@ -0,0 +1,28 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (id, created_at, owner_id, name, secret_hash, redirect_uris)
+VALUES ($1, NOW(), $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients WHERE id = $1;
+
+-- name: ListOAuthClients :many
+SELECT * FROM oauth_clients WHERE owner_id = $1 ORDER BY created_at;
+
+-- name: DeleteOAuthClient :execrows
+DELETE FROM oauth_clients WHERE id = $1 AND owner_id = $2;
+
+-- name: CreateAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes (
+    code_hash, created_at, expires_at, client_id, user_id, redirect_uri, scopes, code_challenge, family_id
+)
+VALUES ($1, NOW(), $2, $3, $4, $5, $6, $7, $8);
+
+-- name: GetAuthorizationCodeForUpdate :one
+SELECT * FROM oauth_authorization_codes WHERE code_hash = $1 FOR UPDATE;
+
+-- name: UseAuthorizationCode :exec
+UPDATE oauth_authorization_codes SET used_at = NOW() WHERE code_hash = $1;
+
+-- name: DeleteExpiredAuthorizationCodes :exec
+DELETE FROM oauth_authorization_codes WHERE expires_at < NOW();
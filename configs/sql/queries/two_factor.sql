@@ -0,0 +1,36 @@
+-- name: StartTOTPEnrolment :execrows
+INSERT INTO totp_credentials (user_id, secret, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret, created_at = NOW(), last_used_step = 0
+WHERE totp_credentials.confirmed_at IS NULL;
+
+-- name: GetTOTPCredential :one
+SELECT * FROM totp_credentials WHERE user_id = $1;
+
+-- name: ConfirmTOTP :execrows
+UPDATE totp_credentials
+SET confirmed_at = NOW(), last_used_step = sqlc.arg('step')
+WHERE user_id = sqlc.arg('user_id') AND secret = sqlc.arg('secret') AND confirmed_at IS NULL;
+
+-- name: UseTOTPStep :execrows
+UPDATE totp_credentials
+SET last_used_step = sqlc.arg('step')
+WHERE user_id = sqlc.arg('user_id') AND confirmed_at IS NOT NULL AND last_used_step < sqlc.arg('step');
+
+-- name: IsTwoFactorEnabled :one
+SELECT EXISTS (
+    SELECT 1 FROM totp_credentials WHERE user_id = $1 AND confirmed_at IS NOT NULL
+) AS enabled;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes WHERE user_id = $1;
+
+-- name: CreateRecoveryCodes :exec
+INSERT INTO recovery_codes (user_id, code_hash)
+SELECT sqlc.arg('user_id')::uuid, unnest(sqlc.arg('code_hashes')::text[]);
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
@@ -1,11 +1,15 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, username, display_name, bio, avatar_url)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
 )
 RETURNING *;
 
@@ -14,18 +18,37 @@ RETURNING *;
 UPDATE users
 SET
     updated_at = NOW(),
-    email = $1,
-    hashed_password = $2
-WHERE id = $3
+    email = COALESCE(sqlc.narg('email'), email),
+    hashed_password = COALESCE(sqlc.narg('hashed_password'), hashed_password),
+    username = COALESCE(sqlc.narg('username'), username),
+    display_name = COALESCE(sqlc.narg('display_name'), display_name),
+    bio = COALESCE(sqlc.narg('bio'), bio),
+    avatar_url = COALESCE(sqlc.narg('avatar_url'), avatar_url),
+    email_verified_at = CASE
+        WHEN COALESCE(sqlc.narg('email'), email) = email THEN email_verified_at
+    END
+WHERE id = sqlc.arg('id')
 RETURNING *;
 
+-- name: GetUser :one
+SELECT * FROM users WHERE id = $1;
+
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE email = $1;
 
+-- name: GetUserProfile :one
+SELECT
+    id, created_at, username, display_name, bio, avatar_url, is_chirpy_red,
+    (SELECT COUNT(*) FROM chirps WHERE chirps.user_id = users.id AND chirps.deleted_at IS NULL)::int AS chirp_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.followee_id = users.id)::int AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.follower_id = users.id)::int AS following_count
+FROM users
+WHERE lower(username) = lower(sqlc.arg('username'));
+
 -- name: GetUserFromRefreshToken :one
-SELECT rt.token, u.id, u.email FROM users u
+SELECT rt.token_hash, u.id, u.email FROM users u
 JOIN refresh_tokens rt ON u.id = rt.user_id
-WHERE rt.token = $1 AND rt.expires_at > NOW() AND rt.revoked_at IS NULL;
+WHERE rt.token_hash = $1 AND rt.expires_at > NOW() AND rt.revoked_at IS NULL;
 
 
 -- name: UpdateUserIsChirpyRed :one
@@ -33,4 +56,29 @@ UPDATE users
 SET
     is_chirpy_red = $1
 WHERE id = $2
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: MarkEmailVerified :execrows
+UPDATE users
+SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND email = $2;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $1, updated_at = NOW()
+WHERE id = $2;
+
+-- name: RehashUserPassword :exec
+UPDATE users
+SET hashed_password = sqlc.arg('new_hash')
+WHERE id = sqlc.arg('id') AND hashed_password = sqlc.arg('old_hash');
+
+-- name: SuspendUser :execrows
+UPDATE users
+SET suspended_at = COALESCE(suspended_at, NOW()), updated_at = NOW()
+WHERE id = $1;
+
+-- name: UnsuspendUser :execrows
+UPDATE users
+SET suspended_at = NULL, updated_at = NOW()
+WHERE id = $1;
@@ -0,0 +1,12 @@
+-- name: CreateVerificationToken :exec
+INSERT INTO verification_tokens (token_hash, user_id, email, created_at, expires_at)
+VALUES ($1, $2, $3, NOW(), $4);
+
+-- name: ConsumeVerificationToken :one
+UPDATE verification_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING user_id, email;
+
+-- name: DeleteVerificationTokensForUser :exec
+DELETE FROM verification_tokens WHERE user_id = $1;
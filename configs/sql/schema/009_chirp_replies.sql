@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD in_reply_to UUID REFERENCES chirps(id) ON DELETE SET NULL,
+ADD deleted_at TIMESTAMP;
+
+CREATE INDEX chirps_in_reply_to_created_at_id_idx ON chirps (in_reply_to, created_at, id);
+
+-- +goose Down
+DROP INDEX chirps_in_reply_to_created_at_id_idx;
+ALTER TABLE chirps
+DROP COLUMN deleted_at,
+DROP COLUMN in_reply_to;
@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD rechirp_of UUID REFERENCES chirps(id) ON DELETE CASCADE,
+ADD quote_of UUID REFERENCES chirps(id) ON DELETE SET NULL,
+ADD CONSTRAINT chirps_rechirp_has_no_other_reference CHECK (
+    rechirp_of IS NULL OR (quote_of IS NULL AND in_reply_to IS NULL)
+);
+
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_idx ON chirps (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL;
+CREATE INDEX chirps_quote_of_idx ON chirps (quote_of);
+
+-- +goose Down
+DROP INDEX chirps_quote_of_idx;
+DROP INDEX chirps_user_id_rechirp_of_idx;
+ALTER TABLE chirps
+DROP CONSTRAINT chirps_rechirp_has_no_other_reference,
+DROP COLUMN quote_of,
+DROP COLUMN rechirp_of;
@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE users
+ADD email_verified_at TIMESTAMP;
+
+CREATE TABLE verification_tokens (
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX verification_tokens_user_id_idx ON verification_tokens (user_id);
+
+-- +goose Down
+DROP TABLE verification_tokens;
+ALTER TABLE users DROP COLUMN email_verified_at;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Rehash existing tokens in place so current sessions keep working. Tokens
+-- are hex strings, so hashing their text matches auth.HashToken.
+ALTER TABLE refresh_tokens DROP CONSTRAINT refresh_tokens_replaced_by_fkey;
+
+UPDATE refresh_tokens
+SET token = encode(sha256(token::bytea), 'hex'),
+    replaced_by = encode(sha256(replaced_by::bytea), 'hex');
+
+ALTER TABLE refresh_tokens RENAME COLUMN token TO token_hash;
+
+ALTER TABLE refresh_tokens
+ADD CONSTRAINT refresh_tokens_replaced_by_fkey
+FOREIGN KEY (replaced_by) REFERENCES refresh_tokens(token_hash) ON DELETE SET NULL;
+
+-- +goose Down
+-- Hashes cannot be turned back into tokens, so every session is ended.
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens RENAME COLUMN token_hash TO token;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE revoked_access_tokens (
+    jti TEXT PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX revoked_access_tokens_expires_at_idx ON revoked_access_tokens (expires_at);
+
+-- Access tokens for a user issued before not_before are rejected.
+CREATE TABLE access_token_cutoffs (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    not_before TIMESTAMP NOT NULL
+);
+
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN suspended_at;
+DROP TABLE access_token_cutoffs;
+DROP TABLE revoked_access_tokens;
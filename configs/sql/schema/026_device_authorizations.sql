@@ -0,0 +1,17 @@
+-- +goose Up
+-- Pending logins from devices such as terminals. A row is deleted once its
+-- device has collected the tokens, or after it expires.
+CREATE TABLE device_authorizations (
+    device_code_hash TEXT PRIMARY KEY,
+    user_code TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    poll_interval INTEGER NOT NULL,
+    last_polled_at TIMESTAMP,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    approved_at TIMESTAMP,
+    denied_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE device_authorizations;
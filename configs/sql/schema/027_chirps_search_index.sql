@@ -0,0 +1,14 @@
+-- +goose Up
+-- Index the text search expression instead of storing it, so queries that
+-- read whole chirps do not carry the vector along.
+ALTER TABLE chirps DROP COLUMN search_vector;
+
+CREATE INDEX chirps_search_idx ON chirps USING GIN (to_tsvector('english', body));
+
+-- +goose Down
+DROP INDEX chirps_search_idx;
+
+ALTER TABLE chirps
+ADD search_vector TSVECTOR NOT NULL GENERATED ALWAYS AS (to_tsvector('english', body)) STORED;
+
+CREATE INDEX chirps_search_vector_idx ON chirps USING GIN (search_vector);
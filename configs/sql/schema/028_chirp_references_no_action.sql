@@ -0,0 +1,15 @@
+-- +goose Up
+-- Replies and quotes keep their parent from being deleted, so a chirp that
+-- gains one while it is being deleted is tombstoned rather than orphaning it.
+ALTER TABLE chirps
+DROP CONSTRAINT chirps_in_reply_to_fkey,
+ADD CONSTRAINT chirps_in_reply_to_fkey FOREIGN KEY (in_reply_to) REFERENCES chirps(id),
+DROP CONSTRAINT chirps_quote_of_fkey,
+ADD CONSTRAINT chirps_quote_of_fkey FOREIGN KEY (quote_of) REFERENCES chirps(id);
+
+-- +goose Down
+ALTER TABLE chirps
+DROP CONSTRAINT chirps_quote_of_fkey,
+ADD CONSTRAINT chirps_quote_of_fkey FOREIGN KEY (quote_of) REFERENCES chirps(id) ON DELETE SET NULL,
+DROP CONSTRAINT chirps_in_reply_to_fkey,
+ADD CONSTRAINT chirps_in_reply_to_fkey FOREIGN KEY (in_reply_to) REFERENCES chirps(id) ON DELETE SET NULL;
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+// SuspendUserHandler suspends the account in the path. The user is signed
+// out everywhere, including access tokens that have not expired yet, and
+// cannot log in again until unsuspended. Suspending twice is not an error.
+func SuspendUserHandler(db *database.Queries, denylist auth.Denylist, adminAPIKey string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isAdmin(r, adminAPIKey) {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", nil)
+			return
+		}
+
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		suspended, err := db.SuspendUser(r.Context(), userID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not suspend user", err)
+			return
+		}
+		if suspended == 0 {
+			utils.RespondWithError(w, http.StatusNotFound, "User not found", nil)
+			return
+		}
+
+		if err = signOutEverywhere(r.Context(), db, denylist, userID); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not revoke sessions", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// UnsuspendUserHandler lets a suspended user log in again.
+func UnsuspendUserHandler(db *database.Queries, adminAPIKey string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isAdmin(r, adminAPIKey) {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", nil)
+			return
+		}
+
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		unsuspended, err := db.UnsuspendUser(r.Context(), userID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not unsuspend user", err)
+			return
+		}
+		if unsuspended == 0 {
+			utils.RespondWithError(w, http.StatusNotFound, "User not found", nil)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// isAdmin reports whether r carries the admin API key. An empty key
+// disables the admin endpoints.
+func isAdmin(r *http.Request, adminAPIKey string) bool {
+	apiKey, err := auth.GetAPIKey(r.Header)
+	return err == nil && adminAPIKey != "" && subtle.ConstantTimeCompare([]byte(apiKey), []byte(adminAPIKey)) == 1
+}
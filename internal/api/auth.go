@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+// authenticate returns the ID of the user whose access token was sent with
+// the request. Only tokens from logging in to Chirpy itself are accepted;
+// endpoints open to OAuth clients and personal access tokens use authorize.
+func authenticate(r *http.Request, keys *auth.Keyring) (uuid.UUID, error) {
+	return authorize(r, keys, "")
+}
+
+// authorize returns the ID of the user whose access token or personal
+// access token was sent with the request, if the token may be used for scope.
+func authorize(r *http.Request, keys *auth.Keyring, scope string) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return keys.Authorize(r.Context(), token, scope)
+}
+
+// respondAuthError responds to a request whose access token was rejected.
+// A valid token without the scope gets 403 and a WWW-Authenticate header
+// naming the scope it lacks, as RFC 6750 describes; anything else gets 401.
+func respondAuthError(w http.ResponseWriter, scope string, err error) {
+	if !errors.Is(err, auth.ErrInsufficientScope) {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+	if scope == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+		utils.RespondWithError(w, http.StatusForbidden, "This endpoint needs an access token from logging in to Chirpy", err)
+		return
+	}
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, scope))
+	utils.RespondWithError(w, http.StatusForbidden, "Access token lacks the "+scope+" scope", err)
+}
+
+// viewer identifies the caller of an endpoint that does not require
+// authentication. It returns a null ID when no usable access token was sent,
+// so a stale or malformed token reads the endpoint anonymously.
+// OAuth clients need the chirps:read scope to read as the user.
+func viewer(r *http.Request, keys *auth.Keyring) uuid.NullUUID {
+	userID, err := authorize(r, keys, auth.ScopeChirpsRead)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}
+}
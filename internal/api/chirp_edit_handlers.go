@@ -0,0 +1,180 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+var (
+	errNotChirpOwner      = errors.New("you are not the owner of this chirp")
+	errRechirpNotEditable = errors.New("rechirps cannot be edited")
+	errQuoteNeedsBody     = errors.New("a quote chirp needs a body")
+)
+
+// ChirpRevision is a body a chirp had before it was edited. CreatedAt is
+// when the body was written and ReplacedAt when an edit replaced it.
+type ChirpRevision struct {
+	ID         uuid.UUID `json:"id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// ChirpRevisions is the edit history of a chirp, most recent edit first.
+type ChirpRevisions struct {
+	ChirpID   uuid.UUID       `json:"chirp_id"`
+	Revisions []ChirpRevision `json:"revisions"`
+}
+
+// UpdateChirpHandler replaces the body of a chirp owned by the caller. The
+// previous body is kept as a revision and the chirp's hashtags and mentions
+// are re-extracted in the same transaction. Resubmitting the current body
+// changes nothing.
+func UpdateChirpHandler(dbConn *sql.DB, db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	type RequestPayload struct {
+		Body string `json:"body"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		chirpID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		userID, err := authorize(r, keys, auth.ScopeChirpsWrite)
+		if err != nil {
+			respondAuthError(w, auth.ScopeChirpsWrite, err)
+			return
+		}
+
+		var requestPayload RequestPayload
+		if decodeErr := json.NewDecoder(r.Body).Decode(&requestPayload); decodeErr != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", decodeErr)
+			return
+		}
+
+		cleanedBody, err := validateChirpBody(requestPayload.Body)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		var dbChirp database.Chirp
+		err = db.InTx(r.Context(), dbConn, func(qtx *database.Queries) error {
+			current, getErr := qtx.GetChirpForUpdate(r.Context(), chirpID)
+			if errors.Is(getErr, sql.ErrNoRows) || current.DeletedAt.Valid {
+				return errChirpNotFound
+			}
+			if getErr != nil {
+				return getErr
+			}
+			switch {
+			case current.UserID != userID:
+				return errNotChirpOwner
+			case current.RechirpOf.Valid:
+				return errRechirpNotEditable
+			case current.QuoteOf.Valid && strings.TrimSpace(cleanedBody) == "":
+				return errQuoteNeedsBody
+			case current.Body == cleanedBody:
+				dbChirp = current
+				return nil
+			}
+
+			// The revision's created_at is when the replaced body was written.
+			writtenAt := current.CreatedAt
+			if current.EditedAt.Valid {
+				writtenAt = current.EditedAt.Time
+			}
+			revErr := qtx.CreateChirpRevision(r.Context(), database.CreateChirpRevisionParams{
+				ChirpID:   chirpID,
+				Body:      current.Body,
+				CreatedAt: writtenAt,
+			})
+			if revErr != nil {
+				return revErr
+			}
+
+			var updateErr error
+			dbChirp, updateErr = qtx.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+				Body: cleanedBody,
+				ID:   chirpID,
+			})
+			if updateErr != nil {
+				return updateErr
+			}
+			return indexChirpBody(r.Context(), qtx, dbChirp)
+		})
+		switch {
+		case errors.Is(err, errChirpNotFound):
+			utils.RespondWithError(w, http.StatusNotFound, "Chirp not found", err)
+			return
+		case errors.Is(err, errNotChirpOwner):
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden", err)
+			return
+		case errors.Is(err, errRechirpNotEditable), errors.Is(err, errQuoteNeedsBody):
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		case err != nil:
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not update chirp", err)
+			return
+		}
+
+		chirp, err := presentChirp(r.Context(), db, uuid.NullUUID{UUID: userID, Valid: true}, dbChirp)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not update chirp", err)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, chirp)
+	}
+}
+
+// ListChirpRevisionsHandler returns the previous bodies of a chirp. Deleting
+// a chirp discards its history, so deleted chirps have none to show.
+func ListChirpRevisionsHandler(db *database.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chirpID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		dbChirp, err := db.GetChirp(r.Context(), chirpID)
+		if err != nil || dbChirp.DeletedAt.Valid {
+			utils.RespondWithError(w, http.StatusNotFound, "Chirp not found", err)
+			return
+		}
+
+		dbRevisions, err := db.ListChirpRevisions(r.Context(), chirpID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not list revisions", err)
+			return
+		}
+
+		history := ChirpRevisions{
+			ChirpID:   chirpID,
+			Revisions: make([]ChirpRevision, len(dbRevisions)),
+		}
+		for i, revision := range dbRevisions {
+			history.Revisions[i] = ChirpRevision{
+				ID:         revision.ID,
+				Body:       revision.Body,
+				CreatedAt:  revision.CreatedAt,
+				ReplacedAt: revision.ReplacedAt,
+			}
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, history)
+	}
+}
@@ -2,10 +2,10 @@ package api
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
-	"sort"
 	"strings"
 	"time"
 
@@ -13,41 +13,78 @@ import (
 
 	"github.com/Myles-J/chirpy/internal/auth"
 	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/pagination"
 	"github.com/Myles-J/chirpy/internal/utils"
 )
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Body      string     `json:"body"`
+	UserID    uuid.UUID  `json:"user_id"`
+	InReplyTo *uuid.UUID `json:"in_reply_to"`
+	RechirpOf *uuid.UUID `json:"rechirp_of"`
+	QuoteOf   *uuid.UUID `json:"quote_of"`
+	LikeCount int32      `json:"like_count"`
+	LikedByMe bool       `json:"liked_by_me"`
+	Edited    bool       `json:"edited"`
+	Mentions  []Mention  `json:"mentions"`
+	// Deleted marks a tombstone: a deleted chirp kept so its replies and
+	// quotes stay attached. Its body is always empty.
+	Deleted bool `json:"deleted,omitempty"`
+
+	// Rechirped and Quoted embed the chirp referenced by RechirpOf or QuoteOf.
+	Rechirped *Chirp `json:"rechirped,omitempty"`
+	Quoted    *Chirp `json:"quoted,omitempty"`
 }
 
-func CreateChirpHandler(db *database.Queries, tokenSecret string) http.HandlerFunc {
-	const maxChirpLength = 140
-	badWords := map[string]struct{}{
-		"kerfuffle": {},
-		"sharbert":  {},
-		"fornax":    {},
-	}
+// ChirpPage is one page of a chirp listing. NextCursor is empty on the last page.
+type ChirpPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+const maxChirpLength = 140
 
+var errChirpTooLong = errors.New("chirp is too long")
+
+// CreateChirpHandler posts a chirp, rechirp or quote chirp for the caller.
+// If requireVerifiedEmail is set, only users who have verified their email
+// address may post.
+func CreateChirpHandler(
+	dbConn *sql.DB,
+	db *database.Queries,
+	keys *auth.Keyring,
+	requireVerifiedEmail bool,
+) http.HandlerFunc {
+	// RechirpOf reposts another chirp as-is and must come without a body.
+	// QuoteOf attaches another chirp to a new body.
 	type RequestPayload struct {
-		Body   string    `json:"body"`
-		UserID uuid.UUID `json:"user_id"`
+		Body      string     `json:"body"`
+		UserID    uuid.UUID  `json:"user_id"`
+		InReplyTo *uuid.UUID `json:"in_reply_to"`
+		RechirpOf *uuid.UUID `json:"rechirp_of"`
+		QuoteOf   *uuid.UUID `json:"quote_of"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		token, err := auth.GetBearerToken(r.Header)
+		userID, err := authorize(r, keys, auth.ScopeChirpsWrite)
 		if err != nil {
-			utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+			respondAuthError(w, auth.ScopeChirpsWrite, err)
 			return
 		}
 
-		userID, validateJWTError := auth.ValidateJWT(token, tokenSecret)
-		if validateJWTError != nil {
-			utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", validateJWTError)
-			return
+		if requireVerifiedEmail {
+			dbUser, getUserErr := db.GetUser(r.Context(), userID)
+			if getUserErr != nil {
+				utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", getUserErr)
+				return
+			}
+			if !dbUser.EmailVerifiedAt.Valid {
+				utils.RespondWithError(w, http.StatusForbidden, "Verify your email address before chirping", nil)
+				return
+			}
 		}
 
 		var requestPayload RequestPayload
@@ -56,87 +93,145 @@ func CreateChirpHandler(db *database.Queries, tokenSecret string) http.HandlerFu
 			return
 		}
 
-		if len(requestPayload.Body) > maxChirpLength {
-			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", errors.New("chirp is too long"))
+		cleanedBody, err := validateChirpBody(requestPayload.Body)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
 			return
 		}
 
-		cleanedBody := getCleanedBody(requestPayload.Body, badWords)
+		if requestPayload.RechirpOf != nil &&
+			(requestPayload.Body != "" || requestPayload.InReplyTo != nil || requestPayload.QuoteOf != nil) {
+			utils.RespondWithError(w, http.StatusBadRequest, "A rechirp cannot have a body, reply or quote", nil)
+			return
+		}
+		if requestPayload.QuoteOf != nil && strings.TrimSpace(requestPayload.Body) == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "A quote chirp needs a body", nil)
+			return
+		}
 
-		dbChirp, createChirpErr := db.CreateChirp(context.Background(), database.CreateChirpParams{
-			Body:   cleanedBody,
-			UserID: userID,
-		})
-		if createChirpErr != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, "Could not create chirp", createChirpErr)
+		inReplyTo, err := resolveChirpReference(r.Context(), db, requestPayload.InReplyTo)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Chirp being replied to does not exist", err)
+			return
+		}
+		rechirpOf, err := resolveChirpReference(r.Context(), db, requestPayload.RechirpOf)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Chirp being rechirped does not exist", err)
+			return
+		}
+		quoteOf, err := resolveChirpReference(r.Context(), db, requestPayload.QuoteOf)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Chirp being quoted does not exist", err)
 			return
 		}
 
-		utils.RespondWithJSON(w, http.StatusCreated, Chirp{
-			ID:        dbChirp.ID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			Body:      dbChirp.Body,
-			UserID:    userID,
+		var dbChirp database.Chirp
+		err = db.InTx(r.Context(), dbConn, func(qtx *database.Queries) error {
+			var createErr error
+			dbChirp, createErr = qtx.CreateChirp(r.Context(), database.CreateChirpParams{
+				Body:      cleanedBody,
+				UserID:    userID,
+				InReplyTo: inReplyTo,
+				RechirpOf: rechirpOf,
+				QuoteOf:   quoteOf,
+			})
+			if createErr != nil {
+				return createErr
+			}
+			return indexChirpBody(r.Context(), qtx, dbChirp)
 		})
+		if err != nil {
+			if database.IsUniqueViolation(err) {
+				utils.RespondWithError(w, http.StatusConflict, "You have already rechirped this chirp", err)
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not create chirp", err)
+			return
+		}
+
+		chirp, err := presentChirp(r.Context(), db, uuid.NullUUID{UUID: userID, Valid: true}, dbChirp)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not create chirp", err)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusCreated, chirp)
 	}
 }
 
-func ListChirpsHandler(db *database.Queries) http.HandlerFunc {
+// ListChirpsHandler returns a page of chirps ordered by creation time.
+// Clients walk the list by passing the returned next_cursor back as cursor.
+func ListChirpsHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
+		ctx := r.Context()
+		viewerID := viewer(r, keys)
+
 		query := r.URL.Query()
 		authorIDStr := query.Get("author_id")
 		sortParam := query.Get("sort")
 
-		var (
-			dbChirps []database.Chirp
-			err      error
-		)
+		limit, err := pagination.ParseLimit(query.Get("limit"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
 
+		var authorID uuid.NullUUID
 		if authorIDStr != "" {
 			parsedAuthorID, parseErr := uuid.Parse(authorIDStr)
 			if parseErr != nil {
 				utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", parseErr)
 				return
 			}
-			dbChirps, err = db.ListChirpsByAuthor(ctx, parsedAuthorID)
-		} else {
-			dbChirps, err = db.ListChirps(ctx)
+			authorID = uuid.NullUUID{UUID: parsedAuthorID, Valid: true}
 		}
+
+		cursorCreatedAt, cursorID, err := parseCursor(r)
 		if err != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error", err)
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
 			return
 		}
 
+		// Fetch one extra row so we know whether another page follows.
+		var dbChirps []database.Chirp
 		switch sortParam {
-		case "desc":
-			sort.Slice(dbChirps, func(i, j int) bool {
-				return dbChirps[i].CreatedAt.After(dbChirps[j].CreatedAt)
+		case "", "asc":
+			dbChirps, err = db.ListChirpsAsc(ctx, database.ListChirpsAscParams{
+				AuthorID:        authorID,
+				CursorCreatedAt: cursorCreatedAt,
+				CursorID:        cursorID,
+				PageLimit:       limit + 1,
 			})
-		case "asc":
-			sort.Slice(dbChirps, func(i, j int) bool {
-				return dbChirps[i].CreatedAt.Before(dbChirps[j].CreatedAt)
+		case "desc":
+			dbChirps, err = db.ListChirpsDesc(ctx, database.ListChirpsDescParams{
+				AuthorID:        authorID,
+				CursorCreatedAt: cursorCreatedAt,
+				CursorID:        cursorID,
+				PageLimit:       limit + 1,
 			})
+		default:
+			utils.RespondWithError(w, http.StatusBadRequest, "sort must be 'asc' or 'desc'", nil)
+			return
+		}
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error", err)
+			return
 		}
 
-		chirps := make([]Chirp, len(dbChirps))
-		for i := range dbChirps {
-			chirps[i] = Chirp{
-				ID:        dbChirps[i].ID,
-				CreatedAt: dbChirps[i].CreatedAt,
-				UpdatedAt: dbChirps[i].UpdatedAt,
-				Body:      dbChirps[i].Body,
-				UserID:    dbChirps[i].UserID,
-			}
+		page, err := newChirpPage(ctx, db, viewerID, dbChirps, limit)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error", err)
+			return
 		}
 
-		utils.RespondWithJSON(w, http.StatusOK, chirps)
+		utils.RespondWithJSON(w, http.StatusOK, page)
 	}
 }
 
-func GetChirpHandler(db *database.Queries) http.HandlerFunc {
+func GetChirpHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		viewerID := viewer(r, keys)
+
 		idStr := r.PathValue("id")
 		id, err := uuid.Parse(idStr)
 		if err != nil {
@@ -145,24 +240,22 @@ func GetChirpHandler(db *database.Queries) http.HandlerFunc {
 		}
 
 		dbChirp, err := db.GetChirp(context.Background(), id)
-		if err != nil {
+		if err != nil || dbChirp.DeletedAt.Valid {
 			utils.RespondWithError(w, http.StatusNotFound, "Chirp not found", err)
 			return
 		}
 
-		chirp := Chirp{
-			ID:        dbChirp.ID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			Body:      dbChirp.Body,
-			UserID:    dbChirp.UserID,
+		chirp, err := presentChirp(r.Context(), db, viewerID, dbChirp)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error", err)
+			return
 		}
 
 		utils.RespondWithJSON(w, http.StatusOK, chirp)
 	}
 }
 
-func DeleteChirpHandler(db *database.Queries, tokenSecret string) http.HandlerFunc {
+func DeleteChirpHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		idStr := r.PathValue("id")
 		chirpID, err := uuid.Parse(idStr)
@@ -170,19 +263,13 @@ func DeleteChirpHandler(db *database.Queries, tokenSecret string) http.HandlerFu
 			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
 			return
 		}
-		token, tokenErr := auth.GetBearerToken(r.Header)
-		if tokenErr != nil {
-			utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", tokenErr)
-			return
-		}
-
-		userID, validateJWTError := auth.ValidateJWT(token, tokenSecret)
-		if validateJWTError != nil {
-			utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", validateJWTError)
+		userID, authErr := authorize(r, keys, auth.ScopeChirpsWrite)
+		if authErr != nil {
+			respondAuthError(w, auth.ScopeChirpsWrite, authErr)
 			return
 		}
 		dbChirp, err := db.GetChirp(context.Background(), chirpID)
-		if err != nil {
+		if err != nil || dbChirp.DeletedAt.Valid {
 			utils.RespondWithError(w, http.StatusNotFound, "Chirp not found", err)
 			return
 		}
@@ -192,7 +279,7 @@ func DeleteChirpHandler(db *database.Queries, tokenSecret string) http.HandlerFu
 				w,
 				http.StatusForbidden,
 				"Forbidden",
-				errors.New("you are not the owner of this chirp"),
+				errNotChirpOwner,
 			)
 			return
 		}
@@ -201,7 +288,18 @@ func DeleteChirpHandler(db *database.Queries, tokenSecret string) http.HandlerFu
 			ID:     chirpID,
 			UserID: userID,
 		})
-		if err != nil {
+		// Chirps with replies or quotes are blanked out instead so the thread
+		// stays intact. The foreign keys reject the delete while any remain.
+		if database.IsForeignKeyViolation(err) {
+			err = db.TombstoneChirp(context.Background(), database.TombstoneChirpParams{
+				ID:     chirpID,
+				UserID: userID,
+			})
+			if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, "Could not delete chirp", err)
+				return
+			}
+		} else if err != nil {
 			w.WriteHeader(http.StatusForbidden)
 			return
 		}
@@ -210,6 +308,53 @@ func DeleteChirpHandler(db *database.Queries, tokenSecret string) http.HandlerFu
 	}
 }
 
+// DeleteRechirpHandler undoes the caller's rechirp of the chirp in the path.
+func DeleteRechirpHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chirpID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		userID, err := authorize(r, keys, auth.ScopeChirpsWrite)
+		if err != nil {
+			respondAuthError(w, auth.ScopeChirpsWrite, err)
+			return
+		}
+
+		deleted, err := db.DeleteRechirp(r.Context(), database.DeleteRechirpParams{
+			UserID:    userID,
+			RechirpOf: chirpID,
+		})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not undo rechirp", err)
+			return
+		}
+		if deleted == 0 {
+			utils.RespondWithError(w, http.StatusNotFound, "You have not rechirped this chirp", nil)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// validateChirpBody applies the rules every chirp body must follow, on
+// create and on edit, and returns the body as it should be stored.
+func validateChirpBody(body string) (string, error) {
+	if len(body) > maxChirpLength {
+		return "", errChirpTooLong
+	}
+
+	badWords := map[string]struct{}{
+		"kerfuffle": {},
+		"sharbert":  {},
+		"fornax":    {},
+	}
+	return getCleanedBody(body, badWords), nil
+}
+
 func getCleanedBody(body string, badWords map[string]struct{}) string {
 	words := strings.Split(body, " ")
 	for i, word := range words {
@@ -220,3 +365,37 @@ func getCleanedBody(body string, badWords map[string]struct{}) string {
 	}
 	return strings.Join(words, " ")
 }
+
+// indexChirpBody records the hashtags and mentions in a chirp's body,
+// replacing those of any earlier version.
+func indexChirpBody(ctx context.Context, qtx *database.Queries, dbChirp database.Chirp) error {
+	if err := replaceHashtags(ctx, qtx, dbChirp); err != nil {
+		return err
+	}
+	return replaceMentions(ctx, qtx, dbChirp)
+}
+
+// resolveChirpReference checks that the chirp a new chirp points at exists
+// and has not been deleted. References to a rechirp are redirected to the
+// original chirp. A nil id resolves to a null reference.
+func resolveChirpReference(ctx context.Context, db *database.Queries, id *uuid.UUID) (uuid.NullUUID, error) {
+	if id == nil {
+		return uuid.NullUUID{}, nil
+	}
+
+	dbChirp, err := db.GetChirp(ctx, *id)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	if dbChirp.RechirpOf.Valid {
+		dbChirp, err = db.GetChirp(ctx, dbChirp.RechirpOf.UUID)
+		if err != nil {
+			return uuid.NullUUID{}, err
+		}
+	}
+	if dbChirp.DeletedAt.Valid {
+		return uuid.NullUUID{}, errChirpNotFound
+	}
+
+	return uuid.NullUUID{UUID: dbChirp.ID, Valid: true}, nil
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/pagination"
+)
+
+func chirpFromDB(dbChirp database.Chirp) Chirp {
+	chirp := Chirp{
+		ID:        dbChirp.ID,
+		CreatedAt: dbChirp.CreatedAt,
+		UpdatedAt: dbChirp.UpdatedAt,
+		Body:      dbChirp.Body,
+		UserID:    dbChirp.UserID,
+		LikeCount: dbChirp.LikeCount,
+		Edited:    dbChirp.EditedAt.Valid,
+		Deleted:   dbChirp.DeletedAt.Valid,
+	}
+	if dbChirp.InReplyTo.Valid {
+		chirp.InReplyTo = &dbChirp.InReplyTo.UUID
+	}
+	if dbChirp.RechirpOf.Valid {
+		chirp.RechirpOf = &dbChirp.RechirpOf.UUID
+	}
+	if dbChirp.QuoteOf.Valid {
+		chirp.QuoteOf = &dbChirp.QuoteOf.UUID
+	}
+	return chirp
+}
+
+// presentChirps converts chirps loaded from the database into API chirps,
+// embedding referenced chirps and filling in the fields that depend on who
+// is asking. viewer is null for anonymous requests.
+func presentChirps(
+	ctx context.Context,
+	db *database.Queries,
+	viewer uuid.NullUUID,
+	dbChirps []database.Chirp,
+) ([]Chirp, error) {
+	chirps := make([]Chirp, len(dbChirps))
+	for i := range dbChirps {
+		chirps[i] = chirpFromDB(dbChirps[i])
+	}
+
+	if err := embedReferencedChirps(ctx, db, chirps); err != nil {
+		return nil, err
+	}
+
+	all := withEmbeddedChirps(chirps)
+	if err := attachMentions(ctx, db, all); err != nil {
+		return nil, err
+	}
+	if err := markLikedChirps(ctx, db, viewer, all); err != nil {
+		return nil, err
+	}
+
+	return chirps, nil
+}
+
+// withEmbeddedChirps returns pointers to chirps and the chirps embedded in
+// them, so per-chirp fields can be filled in for both with one query.
+func withEmbeddedChirps(chirps []Chirp) []*Chirp {
+	all := make([]*Chirp, 0, len(chirps))
+	for i := range chirps {
+		all = append(all, &chirps[i])
+		if chirps[i].Rechirped != nil {
+			all = append(all, chirps[i].Rechirped)
+		}
+		if chirps[i].Quoted != nil {
+			all = append(all, chirps[i].Quoted)
+		}
+	}
+	return all
+}
+
+// embedReferencedChirps loads the chirps that rechirps and quotes point at.
+// A referenced chirp that has since been deleted is embedded as a tombstone
+// placeholder so clients can render "this chirp is unavailable".
+func embedReferencedChirps(ctx context.Context, db *database.Queries, chirps []Chirp) error {
+	var ids []uuid.UUID
+	for i := range chirps {
+		if chirps[i].RechirpOf != nil {
+			ids = append(ids, *chirps[i].RechirpOf)
+		}
+		if chirps[i].QuoteOf != nil {
+			ids = append(ids, *chirps[i].QuoteOf)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	dbReferenced, err := db.ListChirpsByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	referenced := make(map[uuid.UUID]database.Chirp, len(dbReferenced))
+	for i := range dbReferenced {
+		referenced[dbReferenced[i].ID] = dbReferenced[i]
+	}
+
+	embed := func(id uuid.UUID) *Chirp {
+		dbChirp, ok := referenced[id]
+		if !ok {
+			return &Chirp{ID: id, Deleted: true}
+		}
+		chirp := chirpFromDB(dbChirp)
+		return &chirp
+	}
+	for i := range chirps {
+		if chirps[i].RechirpOf != nil {
+			chirps[i].Rechirped = embed(*chirps[i].RechirpOf)
+		}
+		if chirps[i].QuoteOf != nil {
+			chirps[i].Quoted = embed(*chirps[i].QuoteOf)
+		}
+	}
+	return nil
+}
+
+// markLikedChirps sets LikedByMe on chirps. It is a no-op for anonymous
+// viewers.
+func markLikedChirps(ctx context.Context, db *database.Queries, viewer uuid.NullUUID, all []*Chirp) error {
+	if !viewer.Valid || len(all) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(all))
+	for i, chirp := range all {
+		ids[i] = chirp.ID
+	}
+
+	likedIDs, err := db.ListLikedChirpIDs(ctx, database.ListLikedChirpIDsParams{
+		UserID:   viewer.UUID,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+
+	liked := make(map[uuid.UUID]struct{}, len(likedIDs))
+	for _, id := range likedIDs {
+		liked[id] = struct{}{}
+	}
+	for _, chirp := range all {
+		_, chirp.LikedByMe = liked[chirp.ID]
+	}
+
+	return nil
+}
+
+func presentChirp(ctx context.Context, db *database.Queries, viewer uuid.NullUUID, dbChirp database.Chirp) (Chirp, error) {
+	chirps, err := presentChirps(ctx, db, viewer, []database.Chirp{dbChirp})
+	if err != nil {
+		return Chirp{}, err
+	}
+	return chirps[0], nil
+}
+
+// newChirpPage presents rows fetched with a limit of pageLimit+1 as a page,
+// emitting a cursor for the last returned chirp if more rows remain.
+func newChirpPage(
+	ctx context.Context,
+	db *database.Queries,
+	viewer uuid.NullUUID,
+	dbChirps []database.Chirp,
+	pageLimit int32,
+) (ChirpPage, error) {
+	var page ChirpPage
+	if len(dbChirps) > int(pageLimit) {
+		dbChirps = dbChirps[:pageLimit]
+		last := dbChirps[len(dbChirps)-1]
+		page.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	chirps, err := presentChirps(ctx, db, viewer, dbChirps)
+	if err != nil {
+		return ChirpPage{}, err
+	}
+	page.Chirps = chirps
+	return page, nil
+}
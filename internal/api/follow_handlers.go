@@ -0,0 +1,163 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/pagination"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+// Follow is one side of a follow relationship: the other user and when the follow started.
+type Follow struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type FollowPage struct {
+	Users      []Follow `json:"users"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// FollowUserHandler makes the caller follow the user in the path.
+// Following someone twice is not an error.
+func FollowUserHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		followeeID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		userID, err := authorize(r, keys, auth.ScopeProfile)
+		if err != nil {
+			respondAuthError(w, auth.ScopeProfile, err)
+			return
+		}
+
+		if userID == followeeID {
+			utils.RespondWithError(w, http.StatusBadRequest, "You cannot follow yourself", nil)
+			return
+		}
+
+		_, err = db.FollowUser(r.Context(), database.FollowUserParams{
+			FollowerID: userID,
+			FolloweeID: followeeID,
+		})
+		if err != nil {
+			if database.IsForeignKeyViolation(err) {
+				utils.RespondWithError(w, http.StatusNotFound, "User not found", err)
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not follow user", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// UnfollowUserHandler makes the caller stop following the user in the path.
+func UnfollowUserHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		followeeID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		userID, err := authorize(r, keys, auth.ScopeProfile)
+		if err != nil {
+			respondAuthError(w, auth.ScopeProfile, err)
+			return
+		}
+
+		removed, err := db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+			FollowerID: userID,
+			FolloweeID: followeeID,
+		})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not unfollow user", err)
+			return
+		}
+		if removed == 0 {
+			utils.RespondWithError(w, http.StatusNotFound, "You are not following this user", nil)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ListFollowersHandler lists the users following the user in the path, newest first.
+func ListFollowersHandler(db *database.Queries) http.HandlerFunc {
+	return listFollowsHandler(func(r *http.Request, params database.ListFollowersParams) ([]Follow, error) {
+		rows, err := db.ListFollowers(r.Context(), params)
+		follows := make([]Follow, len(rows))
+		for i, row := range rows {
+			follows[i] = Follow{UserID: row.UserID, FollowedAt: row.CreatedAt}
+		}
+		return follows, err
+	})
+}
+
+// ListFollowingHandler lists the users followed by the user in the path, newest first.
+func ListFollowingHandler(db *database.Queries) http.HandlerFunc {
+	return listFollowsHandler(func(r *http.Request, params database.ListFollowersParams) ([]Follow, error) {
+		rows, err := db.ListFollowing(r.Context(), database.ListFollowingParams(params))
+		follows := make([]Follow, len(rows))
+		for i, row := range rows {
+			follows[i] = Follow{UserID: row.UserID, FollowedAt: row.CreatedAt}
+		}
+		return follows, err
+	})
+}
+
+// listFollowsHandler serves a page of follows fetched by list. ListFollowers
+// and ListFollowing take identically shaped params, so both are built from
+// database.ListFollowersParams.
+func listFollowsHandler(list func(*http.Request, database.ListFollowersParams) ([]Follow, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+
+		cursorCreatedAt, cursorID, err := parseCursor(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+			return
+		}
+
+		follows, err := list(r, database.ListFollowersParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       limit + 1,
+		})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not list users", err)
+			return
+		}
+
+		page := FollowPage{Users: follows}
+		if len(follows) > int(limit) {
+			page.Users = follows[:limit]
+			last := page.Users[len(page.Users)-1]
+			page.NextCursor = pagination.Cursor{CreatedAt: last.FollowedAt, ID: last.UserID}.Encode()
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, page)
+	}
+}
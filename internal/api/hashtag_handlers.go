@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/chirptext"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/pagination"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+	// trendingHalfLives is how many half-lives fit in the trending window, so
+	// a use at the start of the window counts 1/16 as much as one made now.
+	trendingHalfLives = 4
+)
+
+// TrendingHashtag is a hashtag's activity within the trending window. Score
+// is the number of uses with each use weighted down by its age.
+type TrendingHashtag struct {
+	Tag   string  `json:"tag"`
+	Uses  int32   `json:"uses"`
+	Score float64 `json:"score"`
+}
+
+// TrendingHashtags lists the top hashtags over a window ending around GeneratedAt.
+type TrendingHashtags struct {
+	Window      string            `json:"window"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Hashtags    []TrendingHashtag `json:"hashtags"`
+}
+
+// ListHashtagChirpsHandler returns chirps tagged with the hashtag in the
+// path, newest first. The tag may be given with or without its '#'.
+func ListHashtagChirpsHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		viewerID := viewer(r, keys)
+
+		tag, ok := chirptext.NormalizeHashtag(r.PathValue("tag"))
+		if !ok {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid hashtag", nil)
+			return
+		}
+
+		limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+
+		cursorCreatedAt, cursorID, err := parseCursor(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+			return
+		}
+
+		dbChirps, err := db.ListChirpsByHashtag(r.Context(), database.ListChirpsByHashtagParams{
+			Tag:             tag,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       limit + 1,
+		})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not list chirps", err)
+			return
+		}
+
+		page, err := newChirpPage(r.Context(), db, viewerID, dbChirps, limit)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not list chirps", err)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, page)
+	}
+}
+
+// TrendingHashtagsHandler ranks hashtags by time-decayed usage over a
+// sliding window, 24 hours unless the window query parameter says otherwise
+// (for example window=6h). Each use counts half as much for every quarter of
+// the window that has passed since it was made.
+func TrendingHashtagsHandler(db *database.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		window := defaultTrendingWindow
+		if windowStr := query.Get("window"); windowStr != "" {
+			parsed, err := time.ParseDuration(windowStr)
+			if err != nil || parsed < time.Minute || parsed > maxTrendingWindow {
+				utils.RespondWithError(w, http.StatusBadRequest, "window must be a duration between 1m and 168h", err)
+				return
+			}
+			window = parsed
+		}
+
+		limit, err := pagination.ParseLimit(query.Get("limit"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+
+		rows, err := db.ListTrendingHashtags(r.Context(), database.ListTrendingHashtagsParams{
+			HalfLifeSeconds: (window / trendingHalfLives).Seconds(),
+			WindowSeconds:   window.Seconds(),
+			PageLimit:       limit,
+		})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not load trending hashtags", err)
+			return
+		}
+
+		trending := TrendingHashtags{
+			Window:      window.String(),
+			GeneratedAt: time.Now().UTC(),
+			Hashtags:    make([]TrendingHashtag, len(rows)),
+		}
+		for i, row := range rows {
+			trending.Hashtags[i] = TrendingHashtag{Tag: row.Tag, Uses: row.Uses, Score: row.Score}
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, trending)
+	}
+}
+
+// replaceHashtags stores the hashtags found in a chirp's body, replacing any
+// recorded for an earlier version of it. Tags keep the chirp's creation time
+// so that editing an old chirp does not make its tags trend.
+func replaceHashtags(ctx context.Context, qtx *database.Queries, dbChirp database.Chirp) error {
+	if err := qtx.DeleteChirpHashtags(ctx, dbChirp.ID); err != nil {
+		return err
+	}
+
+	tags := chirptext.Hashtags(dbChirp.Body)
+	if len(tags) == 0 {
+		return nil
+	}
+	return qtx.CreateChirpHashtags(ctx, database.CreateChirpHashtagsParams{
+		ChirpID:   dbChirp.ID,
+		Tags:      tags,
+		CreatedAt: dbChirp.CreatedAt,
+	})
+}
@@ -0,0 +1,19 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+// JWKSHandler publishes the public keys access tokens are verified with,
+// so other services can check tokens without holding a secret. Caches may
+// keep the set for a few minutes, which is why a new key must be published
+// before it becomes current.
+func JWKSHandler(keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		utils.RespondWithJSON(w, http.StatusOK, keys.JWKS())
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+var errChirpNotFound = errors.New("chirp not found")
+
+// LikeStatus is the caller's like state for a chirp after a like or unlike.
+type LikeStatus struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	LikeCount int32     `json:"like_count"`
+	LikedByMe bool      `json:"liked_by_me"`
+}
+
+// LikeChirpHandler records that the caller likes the chirp in the path.
+// Liking a chirp twice is not an error and does not change its count.
+func LikeChirpHandler(dbConn *sql.DB, db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return setLikeHandler(dbConn, db, keys, true)
+}
+
+// UnlikeChirpHandler removes the caller's like from the chirp in the path.
+func UnlikeChirpHandler(dbConn *sql.DB, db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return setLikeHandler(dbConn, db, keys, false)
+}
+
+// setLikeHandler adds or removes a like. The like row and the chirp's
+// like_count change in the same transaction, and the count is only adjusted
+// when a row was actually inserted or deleted, so concurrent requests cannot
+// double count.
+func setLikeHandler(dbConn *sql.DB, db *database.Queries, keys *auth.Keyring, like bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chirpID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		userID, err := authorize(r, keys, auth.ScopeChirpsWrite)
+		if err != nil {
+			respondAuthError(w, auth.ScopeChirpsWrite, err)
+			return
+		}
+
+		status := LikeStatus{ChirpID: chirpID, LikedByMe: like}
+		err = db.InTx(r.Context(), dbConn, func(qtx *database.Queries) error {
+			dbChirp, getErr := qtx.GetChirp(r.Context(), chirpID)
+			if errors.Is(getErr, sql.ErrNoRows) || dbChirp.DeletedAt.Valid {
+				return errChirpNotFound
+			}
+			if getErr != nil {
+				return getErr
+			}
+			status.LikeCount = dbChirp.LikeCount
+
+			var (
+				changed int64
+				delta   int32
+				likeErr error
+			)
+			params := database.CreateLikeParams{UserID: userID, ChirpID: chirpID}
+			if like {
+				changed, likeErr = qtx.CreateLike(r.Context(), params)
+				delta = 1
+			} else {
+				changed, likeErr = qtx.DeleteLike(r.Context(), database.DeleteLikeParams(params))
+				delta = -1
+			}
+			if likeErr != nil || changed == 0 {
+				return likeErr
+			}
+
+			status.LikeCount, likeErr = qtx.AdjustChirpLikeCount(r.Context(), database.AdjustChirpLikeCountParams{
+				Delta: delta,
+				ID:    chirpID,
+			})
+			return likeErr
+		})
+		if err != nil {
+			if errors.Is(err, errChirpNotFound) || database.IsForeignKeyViolation(err) {
+				utils.RespondWithError(w, http.StatusNotFound, "Chirp not found", err)
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not update like", err)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, status)
+	}
+}
@@ -1,17 +1,35 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/Myles-J/chirpy/internal/auth"
 	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/logger"
 	"github.com/Myles-J/chirpy/internal/utils"
 	"github.com/google/uuid"
 )
 
-func LoginHandler(db *database.Queries, jwtSecret string) http.HandlerFunc {
+// LoginHandler checks an email and password. For accounts with two-factor
+// authentication it responds with a challenge token to complete at
+// POST /api/login/2fa instead of signing the user in. Failed attempts are
+// counted by loginThrottle, which makes clients wait once there are too many.
+// Passwords stored with an outdated algorithm or cost are rehashed with
+// hasher once they have been checked.
+func LoginHandler(
+	db *database.Queries,
+	keys *auth.Keyring,
+	loginThrottle *LoginThrottle,
+	hasher auth.PasswordHasher,
+) http.HandlerFunc {
+	login := newPasswordLogin(db, hasher, loginThrottle)
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		var requestPayload struct {
 			Email    string `json:"email"`
@@ -29,80 +47,204 @@ func LoginHandler(db *database.Queries, jwtSecret string) http.HandlerFunc {
 			return
 		}
 
-		dbUser, err := db.GetUserByEmail(r.Context(), requestPayload.Email)
-		if err != nil {
-			// Other database errors during user lookup are internal.
-			// Log the internal error but tell the client there was an issue.
+		dbUser, err := login.check(r.Context(), requestPayload.Email, requestPayload.Password, clientIP(r))
+		var throttled *loginThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			respondLoginThrottled(w, throttled.wait)
+			return
+		case errors.Is(err, errInvalidCredentials):
+			// Unknown email or wrong password; the client is not told which.
+			utils.RespondWithError(w, http.StatusUnauthorized, "Invalid email or password.", nil)
+			return
+		case err != nil:
 			utils.RespondWithError(w, http.StatusInternalServerError, "Could not retrieve user information.", err)
 			return
 		}
 
-		err = auth.CheckPassword(dbUser.HashedPassword, requestPayload.Password)
-		if err != nil {
-			// Password incorrect. Combine with user not found message for security.
-			utils.RespondWithError(w, http.StatusUnauthorized, "Invalid email or password.", nil)
+		if dbUser.SuspendedAt.Valid {
+			utils.RespondWithError(w, http.StatusForbidden, "Account suspended.", nil)
 			return
 		}
 
-		accessToken, err := auth.MakeJWT(dbUser.ID, jwtSecret, 1*time.Hour)
+		twoFactor, err := db.IsTwoFactorEnabled(r.Context(), dbUser.ID)
 		if err != nil {
-			// Error creating access token. This is an internal system issue.
-			utils.RespondWithError(
-				w,
-				http.StatusInternalServerError,
-				"Could not generate access token. Please try again later.",
-				err,
-			)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not retrieve user information.", err)
 			return
 		}
-
-		refreshToken, err := auth.MakeRefreshToken()
-		if err != nil {
-			// Error creating refresh token. This is an internal system issue.
-			utils.RespondWithError(
-				w,
-				http.StatusInternalServerError,
-				"Could not generate refresh token. Please try again later.",
-				err,
-			)
+		if twoFactor {
+			// Failures stay counted until the second factor is checked too,
+			// so a known password does not reset the limit on code guesses.
+			challengeToken, challengeErr := keys.MakeTwoFactorChallenge(dbUser.ID, twoFactorChallengeTTL)
+			if challengeErr != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, "Could not start two-factor login.", challengeErr)
+				return
+			}
+			utils.RespondWithJSON(w, http.StatusOK, TwoFactorChallenge{
+				TwoFactorRequired: true,
+				ChallengeToken:    challengeToken,
+			})
 			return
 		}
 
-		_, err = db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-			Token:     refreshToken,
-			UserID:    dbUser.ID,
-			ExpiresAt: time.Now().UTC().Add(60 * 24 * time.Hour),
-		})
-		if err != nil {
-			// Error saving refresh token. This is likely a database or system issue.
-			utils.RespondWithError(
-				w,
-				http.StatusInternalServerError,
-				"Could not save refresh token. Please try again later.",
-				err,
-			)
+		if err = loginThrottle.RecordSuccess(r.Context(), requestPayload.Email); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not retrieve user information.", err)
 			return
 		}
+		issueSession(w, r, db, keys, dbUser)
+	}
+}
 
-		// Successful login - Respond with user data and tokens
-		type user struct {
-			ID           uuid.UUID `json:"id"`
-			CreatedAt    time.Time `json:"created_at"`
-			UpdatedAt    time.Time `json:"updated_at"`
-			Email        string    `json:"email"`
-			IsChirpyRed  bool      `json:"is_chirpy_red"`
-			Token        string    `json:"token"`
-			RefreshToken string    `json:"refresh_token"`
-		}
+// errInvalidCredentials covers both unknown emails and wrong passwords, so
+// clients cannot tell which emails have accounts.
+var errInvalidCredentials = errors.New("invalid email or password")
+
+// loginThrottledError means a login was refused without checking the
+// password because of too many recent failures.
+type loginThrottledError struct {
+	wait time.Duration
+}
+
+func (e *loginThrottledError) Error() string {
+	return fmt.Sprintf("too many failed logins, retry in %s", e.wait)
+}
+
+// passwordLogin checks emails and passwords for every way of signing in.
+type passwordLogin struct {
+	db            *database.Queries
+	hasher        auth.PasswordHasher
+	loginThrottle *LoginThrottle
+	// dummyHash is checked for unknown emails so they take as long to
+	// reject as wrong passwords.
+	dummyHash string
+}
+
+func newPasswordLogin(db *database.Queries, hasher auth.PasswordHasher, loginThrottle *LoginThrottle) *passwordLogin {
+	// If hashing fails the check for unknown emails is just faster.
+	dummyHash, _ := hasher.Hash(uuid.NewString())
+	return &passwordLogin{db: db, hasher: hasher, loginThrottle: loginThrottle, dummyHash: dummyHash}
+}
+
+// check returns the user with email if password is theirs, rehashing it if
+// it was stored with an outdated algorithm or cost. While the login is
+// throttled it returns a *loginThrottledError without checking anything. A
+// wrong password stays counted as a failure and is reported as
+// errInvalidCredentials. Success is left for the caller to record, since a
+// second factor may still be needed.
+func (l *passwordLogin) check(ctx context.Context, email, password, ip string) (database.User, error) {
+	wait, err := l.loginThrottle.Reserve(ctx, email, ip)
+	if err != nil {
+		return database.User{}, err
+	}
+	if wait > 0 {
+		return database.User{}, &loginThrottledError{wait: wait}
+	}
 
-		utils.RespondWithJSON(w, http.StatusOK, user{
-			ID:           dbUser.ID,
-			CreatedAt:    dbUser.CreatedAt,
-			UpdatedAt:    dbUser.UpdatedAt,
-			Email:        dbUser.Email,
-			IsChirpyRed:  dbUser.IsChirpyRed,
-			Token:        accessToken,
-			RefreshToken: refreshToken,
+	dbUser, err := l.db.GetUserByEmail(ctx, email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, err
+	}
+	hash := dbUser.HashedPassword
+	if err != nil {
+		hash = l.dummyHash
+	}
+
+	if err != nil || l.hasher.Check(hash, password) != nil {
+		return database.User{}, errInvalidCredentials
+	}
+	if err = l.loginThrottle.Release(ctx, email, ip); err != nil {
+		return database.User{}, err
+	}
+
+	if l.hasher.NeedsRehash(dbUser.HashedPassword) {
+		rehashPassword(ctx, l.db, l.hasher, dbUser, password)
+	}
+	return dbUser, nil
+}
+
+// rehashPassword replaces dbUser's stored hash with one made by hasher. It
+// only logs failures, since the old hash still works. The update is skipped
+// if the password changed since dbUser was read.
+func rehashPassword(
+	ctx context.Context,
+	db *database.Queries,
+	hasher auth.PasswordHasher,
+	dbUser database.User,
+	password string,
+) {
+	newHash, err := hasher.Hash(password)
+	if err == nil {
+		err = db.RehashUserPassword(ctx, database.RehashUserPasswordParams{
+			NewHash: newHash,
+			ID:      dbUser.ID,
+			OldHash: dbUser.HashedPassword,
 		})
 	}
+	if err != nil {
+		logger.NewLogger().Error("Could not rehash password", "user_id", dbUser.ID, "error", err)
+	}
+}
+
+// issueSession starts a new session for dbUser and responds with the user
+// and its access and refresh tokens.
+func issueSession(w http.ResponseWriter, r *http.Request, db *database.Queries, keys *auth.Keyring, dbUser database.User) {
+	accessToken, refreshToken, err := startSession(r, db, keys, dbUser.ID)
+	if err != nil {
+		// Error creating or saving the tokens. This is an internal system issue.
+		utils.RespondWithError(
+			w,
+			http.StatusInternalServerError,
+			"Could not start session. Please try again later.",
+			err,
+		)
+		return
+	}
+
+	// Successful login - Respond with user data and tokens
+	type user struct {
+		ID           uuid.UUID `json:"id"`
+		CreatedAt    time.Time `json:"created_at"`
+		UpdatedAt    time.Time `json:"updated_at"`
+		Email        string    `json:"email"`
+		IsChirpyRed  bool      `json:"is_chirpy_red"`
+		Token        string    `json:"token"`
+		RefreshToken string    `json:"refresh_token"`
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, user{
+		ID:           dbUser.ID,
+		CreatedAt:    dbUser.CreatedAt,
+		UpdatedAt:    dbUser.UpdatedAt,
+		Email:        dbUser.Email,
+		IsChirpyRed:  dbUser.IsChirpyRed,
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+	})
+}
+
+// startSession creates a first-party session for userID on the device that
+// sent r and returns its access token and first refresh token.
+func startSession(r *http.Request, db *database.Queries, keys *auth.Keyring, userID uuid.UUID) (string, string, error) {
+	accessToken, err := keys.MakeJWT(userID, AccessTokenTTL)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	_, err = db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+		TokenHash: auth.HashToken(refreshToken),
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(refreshTokenTTL),
+		FamilyID:  uuid.New(),
+		UserAgent: clientUserAgent(r),
+		IpAddress: clientIP(r),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
 }
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/throttle"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+const (
+	accountThrottlePrefix = "account:"
+	ipThrottlePrefix      = "ip:"
+)
+
+// LoginThrottle slows down and then locks out repeated failed logins, both
+// per account and per client address. Failures are counted in the database
+// so every server instance sees them.
+type LoginThrottle struct {
+	dbConn  *sql.DB
+	db      *database.Queries
+	account throttle.Policy
+	ip      throttle.Policy
+}
+
+// NewLoginThrottle returns a LoginThrottle applying account to failures for
+// one email address and ip to failures from one client address.
+func NewLoginThrottle(dbConn *sql.DB, db *database.Queries, account, ip throttle.Policy) *LoginThrottle {
+	return &LoginThrottle{dbConn: dbConn, db: db, account: account, ip: ip}
+}
+
+// Reserve counts an attempt to log in as email from ip as a failure before
+// it is checked, so parallel guesses cannot all start before any of them is
+// counted. If either key must wait it counts nothing and returns how long.
+// Attempts that turn out right are handed back with Release. Unknown emails
+// are throttled like real ones so the response does not reveal which
+// addresses have accounts.
+func (t *LoginThrottle) Reserve(ctx context.Context, email, ip string) (time.Duration, error) {
+	wait, err := reserveAttempt(ctx, t.dbConn, t.db, throttleKeys(email, ip), t.policy)
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+	return 0, t.db.DeleteStaleLoginThrottles(ctx, database.DeleteStaleLoginThrottlesParams{
+		KeyPatterns:  []string{accountThrottlePrefix + "%", ipThrottlePrefix + "%"},
+		ForgetBefore: time.Now().UTC().Add(-max(t.account.Lockout, t.ip.Lockout)),
+	})
+}
+
+// Release takes back the failure Reserve counted for an attempt that was
+// right after all.
+func (t *LoginThrottle) Release(ctx context.Context, email, ip string) error {
+	return t.db.ReleaseLoginAttempt(ctx, throttleKeys(email, ip))
+}
+
+// RecordSuccess clears the failures counted against email. Failures from
+// the client address are kept, so logging in to one account cannot reset
+// the count for guesses at others.
+func (t *LoginThrottle) RecordSuccess(ctx context.Context, email string) error {
+	return t.db.ClearLoginFailures(ctx, accountThrottleKey(email))
+}
+
+// RequestThrottle limits how often a kind of request may be made, such as
+// starting a device login from one client address. Requests are counted
+// with failed logins, under their own key prefix, and each one counts like
+// a failure under the policy.
+type RequestThrottle struct {
+	dbConn *sql.DB
+	db     *database.Queries
+	prefix string
+	policy throttle.Policy
+}
+
+// NewRequestThrottle returns a RequestThrottle applying policy to the
+// requests of the kind called name.
+func NewRequestThrottle(dbConn *sql.DB, db *database.Queries, name string, policy throttle.Policy) *RequestThrottle {
+	return &RequestThrottle{dbConn: dbConn, db: db, prefix: name + ":", policy: policy}
+}
+
+// Reserve counts a request against each of keys, such as the client
+// address it came from, or returns how long it must wait instead.
+func (t *RequestThrottle) Reserve(ctx context.Context, keys ...string) (time.Duration, error) {
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, t.prefix+key)
+	}
+	slices.Sort(prefixed)
+
+	policy := func(string) throttle.Policy { return t.policy }
+	wait, err := reserveAttempt(ctx, t.dbConn, t.db, prefixed, policy)
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+	return 0, t.db.DeleteStaleLoginThrottles(ctx, database.DeleteStaleLoginThrottlesParams{
+		KeyPatterns:  []string{t.prefix + "%"},
+		ForgetBefore: time.Now().UTC().Add(-t.policy.Lockout),
+	})
+}
+
+// reserveAttempt counts an attempt as a failure against every key, which
+// must be sorted, unless one of them must wait under its policy. Then it
+// counts nothing and returns the longest wait.
+func reserveAttempt(
+	ctx context.Context,
+	dbConn *sql.DB,
+	db *database.Queries,
+	keys []string,
+	policy func(key string) throttle.Policy,
+) (time.Duration, error) {
+	now := time.Now().UTC()
+
+	var wait time.Duration
+	err := db.InTx(ctx, dbConn, func(qtx *database.Queries) error {
+		// Rows are locked in key order, so two attempts sharing a key
+		// cannot deadlock.
+		err := qtx.EnsureLoginThrottles(ctx, database.EnsureLoginThrottlesParams{Keys: keys, CreatedAt: now})
+		if err != nil {
+			return err
+		}
+		rows, err := qtx.LockLoginThrottles(ctx, keys)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			state := throttle.State{Failures: int(row.Failures), LastFailure: row.LastFailureAt}
+			wait = max(wait, policy(row.Key).RetryAfter(state, now))
+		}
+		if wait > 0 {
+			return nil
+		}
+
+		for _, key := range keys {
+			err = qtx.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+				Key:          key,
+				FailedAt:     now,
+				ForgetBefore: now.Add(-policy(key).Lockout),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return wait, err
+}
+
+// throttleKeys returns the keys an attempt for email from ip counts
+// against, sorted as their rows are locked.
+func throttleKeys(email, ip string) []string {
+	return []string{accountThrottleKey(email), ipThrottlePrefix + ip}
+}
+
+func (t *LoginThrottle) policy(key string) throttle.Policy {
+	if strings.HasPrefix(key, accountThrottlePrefix) {
+		return t.account
+	}
+	return t.ip
+}
+
+// accountThrottleKey normalises email so changes of case or surrounding
+// space do not buy extra guesses.
+func accountThrottleKey(email string) string {
+	return accountThrottlePrefix + normalizeEmail(email)
+}
+
+// normalizeEmail folds the case and trims the space of email, so requests
+// for one address count together however it is written.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// respondLoginThrottled tells the client to wait before trying again.
+func respondLoginThrottled(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	utils.RespondWithError(w, http.StatusTooManyRequests, "Too many failed login attempts. Please try again later.", nil)
+}
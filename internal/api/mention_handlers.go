@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/chirptext"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/pagination"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+// Mention links part of a chirp's body to a user. Start and End are
+// character offsets into the body, End exclusive, covering the "@handle".
+type Mention struct {
+	UserID uuid.UUID `json:"user_id"`
+	Start  int32     `json:"start"`
+	End    int32     `json:"end"`
+}
+
+// ListMyMentionsHandler returns chirps that mention the caller, newest first.
+func ListMyMentionsHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := authorize(r, keys, auth.ScopeChirpsRead)
+		if err != nil {
+			respondAuthError(w, auth.ScopeChirpsRead, err)
+			return
+		}
+
+		limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+
+		cursorCreatedAt, cursorID, err := parseCursor(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+			return
+		}
+
+		dbChirps, err := db.ListMentioningChirps(r.Context(), database.ListMentioningChirpsParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       limit + 1,
+		})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not list mentions", err)
+			return
+		}
+
+		page, err := newChirpPage(r.Context(), db, uuid.NullUUID{UUID: userID, Valid: true}, dbChirps, limit)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not list mentions", err)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, page)
+	}
+}
+
+// replaceMentions stores the mentions in a chirp's body that name existing
+// users, replacing any recorded for an earlier version of it. Handles that
+// match nobody are left as plain text.
+func replaceMentions(ctx context.Context, qtx *database.Queries, dbChirp database.Chirp) error {
+	if err := qtx.DeleteChirpMentions(ctx, dbChirp.ID); err != nil {
+		return err
+	}
+
+	mentions := chirptext.Mentions(dbChirp.Body)
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	handles := make([]string, len(mentions))
+	for i, mention := range mentions {
+		handles[i] = strings.ToLower(mention.Handle)
+	}
+	users, err := qtx.ResolveUsernames(ctx, handles)
+	if err != nil {
+		return err
+	}
+	userIDs := make(map[string]uuid.UUID, len(users))
+	for _, user := range users {
+		userIDs[strings.ToLower(user.Username.String)] = user.ID
+	}
+
+	params := database.CreateChirpMentionsParams{ChirpID: dbChirp.ID}
+	for _, mention := range mentions {
+		userID, ok := userIDs[strings.ToLower(mention.Handle)]
+		if !ok {
+			continue
+		}
+		params.UserIds = append(params.UserIds, userID)
+		params.StartOffsets = append(params.StartOffsets, int32(mention.Start)) //nolint:gosec // offsets are bounded by the chirp length
+		params.EndOffsets = append(params.EndOffsets, int32(mention.End))       //nolint:gosec // offsets are bounded by the chirp length
+	}
+	if len(params.UserIds) == 0 {
+		return nil
+	}
+	return qtx.CreateChirpMentions(ctx, params)
+}
+
+// attachMentions loads the resolved mentions of chirps. Every chirp gets a
+// non-nil list so clients always see an array.
+func attachMentions(ctx context.Context, db *database.Queries, all []*Chirp) error {
+	if len(all) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(all))
+	for i, chirp := range all {
+		ids[i] = chirp.ID
+	}
+
+	dbMentions, err := db.ListChirpMentions(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	mentions := make(map[uuid.UUID][]Mention)
+	for _, m := range dbMentions {
+		mentions[m.ChirpID] = append(mentions[m.ChirpID], Mention{
+			UserID: m.UserID,
+			Start:  m.StartOffset,
+			End:    m.EndOffset,
+		})
+	}
+	for _, chirp := range all {
+		chirp.Mentions = mentions[chirp.ID]
+		if chirp.Mentions == nil {
+			chirp.Mentions = []Mention{}
+		}
+	}
+
+	return nil
+}
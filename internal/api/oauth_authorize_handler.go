@@ -0,0 +1,234 @@
+package api
+
+import (
+	"database/sql"
+	_ "embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/logger"
+)
+
+// authorizationCodeTTL is how long a client has to redeem an authorization
+// code. Codes travel through the browser, so they should not last long.
+const authorizationCodeTTL = 5 * time.Minute
+
+// authorizeTemplate is the consent page shown at /oauth/authorize.
+//
+//go:embed templates/authorize.html
+var authorizeTemplate string
+
+var (
+	errUnknownClient       = errors.New("unknown client_id")
+	errRedirectURIMismatch = errors.New("redirect_uri is not registered for this client")
+)
+
+// authorizeRequest is a checked authorization request.
+type authorizeRequest struct {
+	client        database.OauthClient
+	redirectURI   string
+	state         string
+	scopes        []string
+	codeChallenge string
+}
+
+// consentPage is the data for authorizeTemplate. Without a Client it shows
+// Error alone, for requests that cannot be sent back to a client.
+type consentPage struct {
+	Client       string
+	Scopes       []string
+	RedirectHost string
+	Hidden       []hiddenField
+	Email        string
+	Error        string
+}
+
+type hiddenField struct {
+	Name, Value string
+}
+
+// OAuthAuthorizeHandler serves the consent page of the authorization code
+// flow. GET shows what the client is asking for; POST signs the user in
+// with their password, and second factor if they have one, and sends them
+// back to the client's redirect URI with a single-use code to exchange at
+// POST /oauth/token. PKCE with S256 is required of every client. Failed
+// sign-ins count towards loginThrottle like those at POST /api/login.
+func OAuthAuthorizeHandler(
+	db *database.Queries,
+	loginThrottle *LoginThrottle,
+	hasher auth.PasswordHasher,
+) http.HandlerFunc {
+	page := template.Must(template.New("authorize").Parse(authorizeTemplate))
+	login := newPasswordLogin(db, hasher, loginThrottle)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := parseAuthorizeRequest(r, db)
+		var oauthErr *oauthError
+		switch {
+		case errors.As(err, &oauthErr):
+			req.redirect(w, r, url.Values{"error": {oauthErr.Code}, "error_description": {oauthErr.Description}})
+			return
+		case errors.Is(err, errUnknownClient), errors.Is(err, errRedirectURIMismatch):
+			// Never redirect to a URI the client did not register.
+			data := consentPage{Error: "The app sent an invalid request: " + err.Error() + "."}
+			renderHTML(w, page, http.StatusBadRequest, data)
+			return
+		case err != nil:
+			logger.NewLogger().Error("Could not check authorization request", "error", err)
+			data := consentPage{Error: "Something went wrong. Please try again later."}
+			renderHTML(w, page, http.StatusInternalServerError, data)
+			return
+		}
+
+		data := req.consentPage()
+		if r.Method == http.MethodGet {
+			renderHTML(w, page, http.StatusOK, data)
+			return
+		}
+
+		if r.PostFormValue("decision") != "allow" {
+			req.redirect(w, r, url.Values{"error": {"access_denied"}})
+			return
+		}
+
+		data.Email = r.PostFormValue("email")
+		dbUser, err := login.checkForm(r)
+		if err != nil {
+			status, message := signInFailure(w, err)
+			data.Error = message
+			renderHTML(w, page, status, data)
+			return
+		}
+
+		code, err := req.issueCode(r, db, dbUser.ID)
+		if err != nil {
+			logger.NewLogger().Error("Could not issue authorization code", "error", err)
+			data.Error = "Something went wrong. Please try again later."
+			renderHTML(w, page, http.StatusInternalServerError, data)
+			return
+		}
+		req.redirect(w, r, url.Values{"code": {code}})
+	}
+}
+
+// parseAuthorizeRequest checks the parameters of an authorization request,
+// from the query string for GET and the form for POST. A client or redirect
+// URI that cannot be trusted is reported as errUnknownClient or
+// errRedirectURIMismatch. Other problems are returned as an *oauthError
+// along with a request that can redirect it to the client.
+func parseAuthorizeRequest(r *http.Request, db *database.Queries) (authorizeRequest, error) {
+	client, err := getOAuthClient(r, db, r.FormValue("client_id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return authorizeRequest{}, errUnknownClient
+	}
+	if err != nil {
+		return authorizeRequest{}, err
+	}
+
+	redirectURI := r.FormValue("redirect_uri")
+	if redirectURI == "" && len(client.RedirectUris) == 1 {
+		redirectURI = client.RedirectUris[0]
+	}
+	if !slices.Contains(client.RedirectUris, redirectURI) {
+		return authorizeRequest{}, errRedirectURIMismatch
+	}
+
+	req := authorizeRequest{client: client, redirectURI: redirectURI, state: r.FormValue("state")}
+	if r.FormValue("response_type") != "code" {
+		return req, &oauthError{Code: "unsupported_response_type", Description: "response_type must be code"}
+	}
+	if r.FormValue("code_challenge_method") != auth.PKCEMethodS256 ||
+		!auth.ValidCodeChallenge(r.FormValue("code_challenge")) {
+		return req, &oauthError{
+			Code:        "invalid_request",
+			Description: "code_challenge with code_challenge_method S256 is required",
+		}
+	}
+	req.codeChallenge = r.FormValue("code_challenge")
+
+	req.scopes, err = auth.ParseScope(r.FormValue("scope"))
+	if err != nil {
+		return req, &oauthError{Code: "invalid_scope", Description: err.Error()}
+	}
+	return req, nil
+}
+
+// consentPage describes req for the user, carrying its parameters through
+// the form in hidden fields.
+func (req authorizeRequest) consentPage() consentPage {
+	descriptions := make([]string, len(req.scopes))
+	for i, scope := range req.scopes {
+		descriptions[i] = auth.DescribeScope(scope)
+	}
+
+	redirectHost := req.redirectURI
+	if u, err := url.Parse(req.redirectURI); err == nil && u.Host != "" {
+		redirectHost = u.Host
+	}
+
+	return consentPage{
+		Client:       req.client.Name,
+		Scopes:       descriptions,
+		RedirectHost: redirectHost,
+		Hidden: []hiddenField{
+			{"response_type", "code"},
+			{"client_id", req.client.ID.String()},
+			{"redirect_uri", req.redirectURI},
+			{"scope", strings.Join(req.scopes, " ")},
+			{"state", req.state},
+			{"code_challenge", req.codeChallenge},
+			{"code_challenge_method", auth.PKCEMethodS256},
+		},
+	}
+}
+
+// issueCode stores a new authorization code granting req to userID and
+// returns it. Expired codes are cleared out at the same time.
+func (req authorizeRequest) issueCode(r *http.Request, db *database.Queries, userID uuid.UUID) (string, error) {
+	code, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = db.CreateAuthorizationCode(r.Context(), database.CreateAuthorizationCodeParams{
+		CodeHash:      auth.HashToken(code),
+		ExpiresAt:     time.Now().UTC().Add(authorizationCodeTTL),
+		ClientID:      req.client.ID,
+		UserID:        userID,
+		RedirectUri:   req.redirectURI,
+		Scopes:        req.scopes,
+		CodeChallenge: req.codeChallenge,
+		FamilyID:      uuid.New(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return code, db.DeleteExpiredAuthorizationCodes(r.Context())
+}
+
+// redirect sends the user back to the client with params, and the state
+// the client sent, added to the redirect URI's query.
+func (req authorizeRequest) redirect(w http.ResponseWriter, r *http.Request, params url.Values) {
+	// Redirect URIs were checked when the client was registered.
+	u, _ := url.Parse(req.redirectURI)
+	query := u.Query()
+	for key, values := range params {
+		if values[0] != "" {
+			query[key] = values
+		}
+	}
+	if req.state != "" {
+		query.Set("state", req.state)
+	}
+	u.RawQuery = query.Encode()
+	http.Redirect(w, r, u.String(), http.StatusSeeOther)
+}
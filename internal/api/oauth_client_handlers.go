@@ -0,0 +1,224 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+const (
+	maxClientNameLength  = 100
+	maxRedirectURIs      = 10
+	maxRedirectURILength = 2048
+)
+
+var (
+	errInvalidClientName   = fmt.Errorf("name must be 1 to %d characters", maxClientNameLength)
+	errInvalidRedirectURIs = fmt.Errorf(
+		"redirect_uris must list 1 to %d https URLs, loopback http URLs or app URLs without fragments",
+		maxRedirectURIs,
+	)
+)
+
+// OAuthClient is a third-party app registered to ask users for access to
+// their accounts.
+type OAuthClient struct {
+	ID           uuid.UUID `json:"client_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Confidential bool      `json:"confidential"`
+	// ClientSecret is only sent when a confidential client is registered.
+	// Chirpy keeps a hash, so it cannot be shown again.
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+func oauthClientFromDB(dbClient database.OauthClient) OAuthClient {
+	return OAuthClient{
+		ID:           dbClient.ID,
+		CreatedAt:    dbClient.CreatedAt,
+		Name:         dbClient.Name,
+		RedirectURIs: dbClient.RedirectUris,
+		Confidential: dbClient.SecretHash.Valid,
+	}
+}
+
+// CreateOAuthClientHandler registers an OAuth client owned by the caller.
+// Confidential clients, which run on a server, get a secret to authenticate
+// at POST /oauth/token. Public clients, such as mobile apps, cannot keep one
+// and rely on PKCE alone.
+func CreateOAuthClientHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	type RequestPayload struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Confidential bool     `json:"confidential"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := authenticate(r, keys)
+		if err != nil {
+			respondAuthError(w, "", err)
+			return
+		}
+
+		var requestPayload RequestPayload
+		if err = json.NewDecoder(r.Body).Decode(&requestPayload); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		name := strings.TrimSpace(requestPayload.Name)
+		if name == "" || utf8.RuneCountInString(name) > maxClientNameLength {
+			utils.RespondWithError(w, http.StatusBadRequest, errInvalidClientName.Error(), errInvalidClientName)
+			return
+		}
+		if !validRedirectURIs(requestPayload.RedirectURIs) {
+			utils.RespondWithError(w, http.StatusBadRequest, errInvalidRedirectURIs.Error(), errInvalidRedirectURIs)
+			return
+		}
+
+		var secret string
+		var secretHash sql.NullString
+		if requestPayload.Confidential {
+			secret, err = auth.MakeRefreshToken()
+			if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, "Could not create client secret", err)
+				return
+			}
+			secretHash = sql.NullString{String: auth.HashToken(secret), Valid: true}
+		}
+
+		dbClient, err := db.CreateOAuthClient(r.Context(), database.CreateOAuthClientParams{
+			ID:           uuid.New(),
+			OwnerID:      userID,
+			Name:         name,
+			SecretHash:   secretHash,
+			RedirectUris: requestPayload.RedirectURIs,
+		})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not register client", err)
+			return
+		}
+
+		client := oauthClientFromDB(dbClient)
+		client.ClientSecret = secret
+		utils.RespondWithJSON(w, http.StatusCreated, client)
+	}
+}
+
+// ListOAuthClientsHandler lists the OAuth clients the caller registered.
+func ListOAuthClientsHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := authenticate(r, keys)
+		if err != nil {
+			respondAuthError(w, "", err)
+			return
+		}
+
+		dbClients, err := db.ListOAuthClients(r.Context(), userID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not list clients", err)
+			return
+		}
+
+		clients := make([]OAuthClient, len(dbClients))
+		for i, dbClient := range dbClients {
+			clients[i] = oauthClientFromDB(dbClient)
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, struct {
+			Clients []OAuthClient `json:"clients"`
+		}{Clients: clients})
+	}
+}
+
+// DeleteOAuthClientHandler removes one of the caller's OAuth clients. Its
+// pending authorization codes and every session users granted it go too.
+func DeleteOAuthClientHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		userID, err := authenticate(r, keys)
+		if err != nil {
+			respondAuthError(w, "", err)
+			return
+		}
+
+		deleted, err := db.DeleteOAuthClient(r.Context(), database.DeleteOAuthClientParams{
+			ID:      clientID,
+			OwnerID: userID,
+		})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not delete client", err)
+			return
+		}
+		if deleted == 0 {
+			utils.RespondWithError(w, http.StatusNotFound, "Client not found", nil)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// getOAuthClient looks up a client by the client_id parameter, reporting
+// unknown and malformed IDs alike as sql.ErrNoRows.
+func getOAuthClient(r *http.Request, db *database.Queries, clientID string) (database.OauthClient, error) {
+	id, err := uuid.Parse(clientID)
+	if err != nil {
+		return database.OauthClient{}, sql.ErrNoRows
+	}
+	return db.GetOAuthClient(r.Context(), id)
+}
+
+// validRedirectURIs checks a client's redirect URIs as RFC 8252 and the
+// OAuth security BCP recommend: https URLs, http URLs on the loopback
+// interface for desktop apps, or private-use schemes such as
+// com.example.app:/callback for mobile apps. None may carry a fragment,
+// since the code is appended to the query.
+func validRedirectURIs(uris []string) bool {
+	if len(uris) == 0 || len(uris) > maxRedirectURIs {
+		return false
+	}
+	return !slices.ContainsFunc(uris, func(uri string) bool {
+		return !validRedirectURI(uri)
+	})
+}
+
+func validRedirectURI(uri string) bool {
+	if len(uri) > maxRedirectURILength {
+		return false
+	}
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Fragment != "" || strings.Contains(uri, "#") {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return u.Host != ""
+	case "http":
+		host := u.Hostname()
+		ip := net.ParseIP(host)
+		return host == "localhost" || ip != nil && ip.IsLoopback()
+	default:
+		// Private-use schemes are reverse domain names, so they have a dot.
+		return strings.Contains(u.Scheme, ".")
+	}
+}
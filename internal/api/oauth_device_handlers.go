@@ -0,0 +1,269 @@
+package api
+
+import (
+	"database/sql"
+	_ "embed"
+	"errors"
+	"html/template"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/logger"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+const (
+	// deviceClientID is the client_id of the Chirpy CLI. The device grant
+	// signs it in like POST /api/login, so no other client may use it.
+	deviceClientID      = "chirpy-cli"
+	deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+	deviceCodeTTL       = 10 * time.Minute
+	// devicePollInterval is how long devices wait between polls at first,
+	// and how much longer each time they poll too soon.
+	devicePollInterval = 5 * time.Second
+	// userCodeAttempts is how many user codes to try when new ones keep
+	// clashing with codes in use.
+	userCodeAttempts = 3
+)
+
+// deviceTemplate is the page at /app/device where users approve devices.
+//
+//go:embed templates/device.html
+var deviceTemplate string
+
+// DeviceCode is the response to a device authorization request. The device
+// shows UserCode and VerificationURI to the user, then polls
+// POST /oauth/token with DeviceCode every Interval seconds.
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// devicePage is the data for deviceTemplate. Done replaces the form once a
+// request has been approved or denied.
+type devicePage struct {
+	UserCode string
+	Email    string
+	Error    string
+	Done     string
+}
+
+// DeviceCodeHandler starts the device authorization grant of RFC 8628 for
+// terminals and other devices that cannot show a sign-in page. The user
+// approves the device at baseURL/app/device on another screen. Anyone can
+// ask, so requests from each client address are limited by deviceThrottle.
+func DeviceCodeHandler(db *database.Queries, deviceThrottle *RequestThrottle, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+
+		if err := r.ParseForm(); err != nil {
+			respondOAuthError(w, &oauthError{Code: "invalid_request", Description: "malformed form body"})
+			return
+		}
+		if r.PostForm.Get("client_id") != deviceClientID {
+			respondOAuthError(w, &oauthError{Code: "invalid_client", Description: "unknown client"})
+			return
+		}
+
+		wait, err := deviceThrottle.Reserve(r.Context(), clientIP(r))
+		if err != nil {
+			respondOAuthError(w, err)
+			return
+		}
+		if wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			utils.RespondWithJSON(w, http.StatusTooManyRequests, &oauthError{
+				Code:        "slow_down",
+				Description: "too many device authorization requests",
+			})
+			return
+		}
+
+		deviceCode, err := auth.MakeRefreshToken()
+		if err != nil {
+			respondOAuthError(w, err)
+			return
+		}
+
+		// Expired requests go first so their user codes can be reused.
+		if err = db.DeleteExpiredDeviceAuthorizations(r.Context()); err != nil {
+			respondOAuthError(w, err)
+			return
+		}
+		var userCode string
+		for range userCodeAttempts {
+			userCode, err = auth.MakeUserCode()
+			if err != nil {
+				break
+			}
+			err = db.CreateDeviceAuthorization(r.Context(), database.CreateDeviceAuthorizationParams{
+				DeviceCodeHash: auth.HashToken(deviceCode),
+				UserCode:       userCode,
+				ExpiresAt:      time.Now().UTC().Add(deviceCodeTTL),
+				PollInterval:   int32(devicePollInterval.Seconds()),
+			})
+			if !database.IsUniqueViolation(err) {
+				break
+			}
+		}
+		if err != nil {
+			respondOAuthError(w, err)
+			return
+		}
+
+		verificationURI := baseURL + "/app/device"
+		utils.RespondWithJSON(w, http.StatusOK, DeviceCode{
+			DeviceCode:              deviceCode,
+			UserCode:                userCode,
+			VerificationURI:         verificationURI,
+			VerificationURIComplete: verificationURI + "?user_code=" + url.QueryEscape(userCode),
+			ExpiresIn:               int(deviceCodeTTL.Seconds()),
+			Interval:                int(devicePollInterval.Seconds()),
+		})
+	}
+}
+
+// DeviceVerificationHandler serves the page where a user approves or denies
+// a device by its user code. Either decision needs the user to sign in,
+// with a second factor if they have one; failures count towards
+// loginThrottle.
+func DeviceVerificationHandler(
+	db *database.Queries,
+	loginThrottle *LoginThrottle,
+	hasher auth.PasswordHasher,
+) http.HandlerFunc {
+	page := template.Must(template.New("device").Parse(deviceTemplate))
+	login := newPasswordLogin(db, hasher, loginThrottle)
+	invalidCode := "That code is invalid or has expired. Start signing in on your device again."
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			renderHTML(w, page, http.StatusOK, devicePage{UserCode: r.URL.Query().Get("user_code")})
+			return
+		}
+
+		userCode := auth.NormalizeUserCode(r.PostFormValue("user_code"))
+		data := devicePage{UserCode: userCode, Email: r.PostFormValue("email")}
+
+		dbUser, err := login.checkForm(r)
+		if err != nil {
+			status, message := signInFailure(w, err)
+			data.Error = message
+			renderHTML(w, page, status, data)
+			return
+		}
+
+		allow := r.PostFormValue("decision") == "allow"
+		var decided int64
+		if allow {
+			decided, err = db.ApproveDeviceAuthorization(r.Context(), database.ApproveDeviceAuthorizationParams{
+				UserID:   uuid.NullUUID{UUID: dbUser.ID, Valid: true},
+				UserCode: userCode,
+			})
+		} else {
+			decided, err = db.DenyDeviceAuthorization(r.Context(), userCode)
+		}
+		if err != nil {
+			logger.NewLogger().Error("Could not decide on device", "error", err)
+			data.Error = "Something went wrong. Please try again later."
+			renderHTML(w, page, http.StatusInternalServerError, data)
+			return
+		}
+		if decided == 0 {
+			data.Error = invalidCode
+			renderHTML(w, page, http.StatusBadRequest, data)
+			return
+		}
+
+		done := "The device was denied access."
+		if allow {
+			done = "Your device is connected. You can return to it now."
+		}
+		renderHTML(w, page, http.StatusOK, devicePage{Done: done})
+	}
+}
+
+// redeemDeviceCode answers a device polling POST /oauth/token. Until the
+// user decides it reports authorization_pending, or slow_down if the device
+// polls before its interval is up. Once approved, the device gets a new
+// session like one from POST /api/login, and the device code is used up.
+func redeemDeviceCode(r *http.Request, dbConn *sql.DB, db *database.Queries, keys *auth.Keyring) (OAuthToken, error) {
+	if r.PostForm.Get("client_id") != deviceClientID {
+		return OAuthToken{}, &oauthError{Code: "invalid_client", Description: "unknown client"}
+	}
+
+	ctx := r.Context()
+	var (
+		accessToken, refreshToken string
+		pending                   *oauthError
+	)
+	err := db.InTx(ctx, dbConn, func(qtx *database.Queries) error {
+		device, getErr := qtx.GetDeviceAuthorizationForUpdate(ctx, auth.HashToken(r.PostForm.Get("device_code")))
+		if errors.Is(getErr, sql.ErrNoRows) {
+			return &oauthError{Code: "invalid_grant", Description: "unknown or already used device code"}
+		}
+		if getErr != nil {
+			return getErr
+		}
+
+		now := time.Now().UTC()
+		switch {
+		case !device.ExpiresAt.After(now):
+			return &oauthError{Code: "expired_token", Description: "the device code has expired"}
+		case device.DeniedAt.Valid:
+			return &oauthError{Code: "access_denied", Description: "the user denied the request"}
+		case device.ApprovedAt.Valid && device.UserID.Valid:
+			if deleteErr := qtx.DeleteDeviceAuthorization(ctx, device.DeviceCodeHash); deleteErr != nil {
+				return deleteErr
+			}
+			dbUser, userErr := qtx.GetUser(ctx, device.UserID.UUID)
+			if userErr != nil {
+				return userErr
+			}
+			if dbUser.SuspendedAt.Valid {
+				return &oauthError{Code: "access_denied", Description: "the account is suspended"}
+			}
+			var sessionErr error
+			accessToken, refreshToken, sessionErr = startSession(r, qtx, keys, dbUser.ID)
+			return sessionErr
+		}
+
+		// The poll must be recorded, so waiting is reported outside the
+		// transaction instead of as an error.
+		interval := time.Duration(device.PollInterval) * time.Second
+		pending = &oauthError{Code: "authorization_pending", Description: "the user has not decided yet"}
+		if device.LastPolledAt.Valid && now.Sub(device.LastPolledAt.Time) < interval {
+			interval += devicePollInterval
+			pending = &oauthError{Code: "slow_down", Description: "poll less often"}
+		}
+		return qtx.RecordDevicePoll(ctx, database.RecordDevicePollParams{
+			LastPolledAt:   sql.NullTime{Time: now, Valid: true},
+			PollInterval:   int32(interval.Seconds()),
+			DeviceCodeHash: device.DeviceCodeHash,
+		})
+	})
+	if err == nil && pending != nil {
+		err = pending
+	}
+	if err != nil {
+		return OAuthToken{}, err
+	}
+
+	return OAuthToken{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(AccessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
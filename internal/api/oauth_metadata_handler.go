@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+// OAuthMetadata describes the OAuth server so clients can configure
+// themselves, as in RFC 8414.
+type OAuthMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+}
+
+// OAuthMetadataHandler publishes the OAuth server metadata for the server
+// at baseURL.
+func OAuthMetadataHandler(baseURL string) http.HandlerFunc {
+	metadata := OAuthMetadata{
+		Issuer:                            baseURL,
+		AuthorizationEndpoint:             baseURL + "/oauth/authorize",
+		TokenEndpoint:                     baseURL + "/oauth/token",
+		DeviceAuthorizationEndpoint:       baseURL + "/oauth/device/code",
+		JWKSURI:                           baseURL + "/.well-known/jwks.json",
+		ScopesSupported:                   auth.Scopes(),
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token", deviceCodeGrantType},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{auth.PKCEMethodS256},
+	}
+
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		utils.RespondWithJSON(w, http.StatusOK, metadata)
+	}
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+// oauthError is an OAuth 2.0 error, sent as JSON from the token endpoint
+// and in the query of redirects from the consent page (RFC 6749 sections
+// 4.1.2.1 and 5.2).
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *oauthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// OAuthToken is a successful response from the token endpoint.
+type OAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// OAuthTokenHandler is the token endpoint of the OAuth server. Clients
+// exchange an authorization code and its PKCE verifier, or a refresh token
+// from an earlier exchange, for a scoped access token and a new refresh
+// token. Refresh tokens rotate exactly as at POST /api/refresh, and one
+// issued to a client only works for that client. Redeeming an authorization
+// code twice revokes the session it started, since the code must have
+// leaked.
+//
+// The Chirpy CLI also polls it with the device code grant, and gets the
+// same first-party tokens as POST /api/login once the user approves it.
+func OAuthTokenHandler(dbConn *sql.DB, db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+
+		if err := r.ParseForm(); err != nil {
+			respondOAuthError(w, &oauthError{Code: "invalid_request", Description: "malformed form body"})
+			return
+		}
+
+		if r.PostForm.Get("grant_type") == deviceCodeGrantType {
+			token, err := redeemDeviceCode(r, dbConn, db, keys)
+			if err != nil {
+				respondOAuthError(w, err)
+				return
+			}
+			utils.RespondWithJSON(w, http.StatusOK, token)
+			return
+		}
+
+		client, err := authenticateClient(r, db)
+		if err != nil {
+			respondOAuthError(w, err)
+			return
+		}
+
+		newRefreshToken, err := auth.MakeRefreshToken()
+		if err != nil {
+			respondOAuthError(w, err)
+			return
+		}
+
+		var grant database.RefreshToken
+		switch grantType := r.PostForm.Get("grant_type"); grantType {
+		case "authorization_code":
+			grant, err = redeemAuthorizationCode(r, dbConn, db, client, newRefreshToken)
+		case "refresh_token":
+			grant, err = rotateRefreshToken(
+				r.Context(), dbConn, db,
+				r.PostForm.Get("refresh_token"), newRefreshToken,
+				uuid.NullUUID{UUID: client.ID, Valid: true},
+			)
+			if errors.Is(err, errInvalidRefreshToken) || errors.Is(err, errRefreshTokenReused) {
+				err = &oauthError{Code: "invalid_grant", Description: err.Error()}
+			}
+		default:
+			err = &oauthError{Code: "unsupported_grant_type", Description: "unknown grant_type"}
+		}
+		if err != nil {
+			respondOAuthError(w, err)
+			return
+		}
+
+		accessToken, err := keys.MakeClientJWT(grant.UserID, client.ID.String(), grant.Scopes, AccessTokenTTL)
+		if err != nil {
+			respondOAuthError(w, err)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, OAuthToken{
+			AccessToken:  accessToken,
+			TokenType:    "Bearer",
+			ExpiresIn:    int(AccessTokenTTL.Seconds()),
+			RefreshToken: newRefreshToken,
+			Scope:        strings.Join(grant.Scopes, " "),
+		})
+	}
+}
+
+// authenticateClient identifies the client making a token request, from
+// HTTP Basic credentials or the client_id and client_secret form fields.
+// Confidential clients must send their secret; public clients have none.
+func authenticateClient(r *http.Request, db *database.Queries) (database.OauthClient, error) {
+	clientID, secret, basic := r.BasicAuth()
+	if basic {
+		// RFC 6749 section 2.3.1 has both parts form-encoded first.
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	invalidClient := &oauthError{Code: "invalid_client", Description: "unknown client or wrong client secret"}
+	client, err := getOAuthClient(r, db, clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return client, invalidClient
+	}
+	if err != nil {
+		return client, err
+	}
+
+	if client.SecretHash.Valid {
+		if subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash.String)) != 1 {
+			return client, invalidClient
+		}
+	} else if secret != "" {
+		return client, invalidClient
+	}
+	return client, nil
+}
+
+// redeemAuthorizationCode exchanges the code in a token request for a new
+// session holding newRefreshToken, and returns its first refresh token.
+func redeemAuthorizationCode(
+	r *http.Request,
+	dbConn *sql.DB,
+	db *database.Queries,
+	client database.OauthClient,
+	newRefreshToken string,
+) (database.RefreshToken, error) {
+	ctx := r.Context()
+	invalidGrant := &oauthError{Code: "invalid_grant", Description: "invalid, expired or already used authorization code"}
+
+	var (
+		created database.RefreshToken
+		reused  bool
+	)
+	err := db.InTx(ctx, dbConn, func(qtx *database.Queries) error {
+		code, getErr := qtx.GetAuthorizationCodeForUpdate(ctx, auth.HashToken(r.PostForm.Get("code")))
+		if errors.Is(getErr, sql.ErrNoRows) {
+			return invalidGrant
+		}
+		if getErr != nil {
+			return getErr
+		}
+
+		// As with refresh tokens, the revocation must commit, so reuse is
+		// reported outside the transaction.
+		if code.UsedAt.Valid {
+			reused = true
+			return qtx.RevokeRefreshTokenFamily(ctx, code.FamilyID)
+		}
+		// The redirect URI may be left out, since PKCE already ties the
+		// code to the client that asked for it.
+		redirectURI := r.PostForm.Get("redirect_uri")
+		if !code.ExpiresAt.After(time.Now().UTC()) ||
+			code.ClientID != client.ID ||
+			redirectURI != "" && redirectURI != code.RedirectUri ||
+			!auth.VerifyPKCE(r.PostForm.Get("code_verifier"), code.CodeChallenge) {
+			return invalidGrant
+		}
+
+		dbUser, userErr := qtx.GetUser(ctx, code.UserID)
+		if userErr != nil {
+			return userErr
+		}
+		if dbUser.SuspendedAt.Valid {
+			return invalidGrant
+		}
+
+		if useErr := qtx.UseAuthorizationCode(ctx, code.CodeHash); useErr != nil {
+			return useErr
+		}
+		var createErr error
+		created, createErr = qtx.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+			TokenHash: auth.HashToken(newRefreshToken),
+			UserID:    code.UserID,
+			ExpiresAt: time.Now().UTC().Add(refreshTokenTTL),
+			FamilyID:  code.FamilyID,
+			UserAgent: clientUserAgent(r),
+			IpAddress: clientIP(r),
+			ClientID:  uuid.NullUUID{UUID: client.ID, Valid: true},
+			Scopes:    code.Scopes,
+		})
+		return createErr
+	})
+	if err == nil && reused {
+		err = invalidGrant
+	}
+	return created, err
+}
+
+// respondOAuthError sends err as an OAuth error response. Errors other than
+// an *oauthError are reported as server_error without their details.
+func respondOAuthError(w http.ResponseWriter, err error) {
+	var oauthErr *oauthError
+	if !errors.As(err, &oauthErr) {
+		utils.RespondWithError(w, http.StatusInternalServerError, "server_error", err)
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == "invalid_client" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+		status = http.StatusUnauthorized
+	}
+	utils.RespondWithJSON(w, status, oauthErr)
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/pagination"
+)
+
+// parseCursor reads the optional cursor query parameter into the nullable
+// pair accepted by the keyset-paginated queries.
+func parseCursor(r *http.Request) (sql.NullTime, uuid.NullUUID, error) {
+	cursorStr := r.URL.Query().Get("cursor")
+	if cursorStr == "" {
+		return sql.NullTime{}, uuid.NullUUID{}, nil
+	}
+
+	cursor, err := pagination.DecodeCursor(cursorStr)
+	if err != nil {
+		return sql.NullTime{}, uuid.NullUUID{}, err
+	}
+
+	return sql.NullTime{Time: cursor.CreatedAt, Valid: true}, uuid.NullUUID{UUID: cursor.ID, Valid: true}, nil
+}
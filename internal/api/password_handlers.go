@@ -0,0 +1,313 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/logger"
+	"github.com/Myles-J/chirpy/internal/mail"
+	"github.com/Myles-J/chirpy/internal/password"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+const (
+	passwordResetTokenTTL = time.Hour
+	passwordResetTimeout  = 30 * time.Second
+)
+
+var errInvalidResetToken = errors.New("reset token is invalid, expired or already used")
+
+// resetPasswordTemplate is the page at /app/reset-password that reset
+// emails link to.
+//
+//go:embed templates/reset_password.html
+var resetPasswordTemplate string
+
+// resetPasswordPage is the data for resetPasswordTemplate. Done replaces the
+// form once the password is changed.
+type resetPasswordPage struct {
+	Token      string
+	Error      string
+	Violations []password.Violation
+	Done       string
+}
+
+// passwordPolicyError carries the rules a new password broke out of the
+// transaction that found them.
+type passwordPolicyError struct {
+	violations []password.Violation
+}
+
+func (e *passwordPolicyError) Error() string {
+	return "password does not meet the requirements"
+}
+
+// ForgotPasswordHandler emails a single-use password reset link to the
+// address in the request if it is the verified email of an account, so an
+// unconfirmed email change cannot be used to take the account over. It
+// responds 202 Accepted, and does the lookup and sending after responding,
+// so the response reveals nothing about which emails are registered.
+// Requests are throttled per client address and per email; ones over the
+// limit get the same response but send nothing.
+func ForgotPasswordHandler(
+	db *database.Queries,
+	resetThrottle *RequestThrottle,
+	mailer mail.Mailer,
+	baseURL string,
+) http.HandlerFunc {
+	type RequestPayload struct {
+		Email string `json:"email"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestPayload RequestPayload
+		if err := json.NewDecoder(r.Body).Decode(&requestPayload); err != nil || requestPayload.Email == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		wait, err := resetThrottle.Reserve(
+			r.Context(),
+			"ip:"+clientIP(r),
+			"email:"+normalizeEmail(requestPayload.Email),
+		)
+		if err != nil {
+			logger.NewLogger().Error("Could not throttle password reset", "error", err)
+		}
+		if err != nil || wait > 0 {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), passwordResetTimeout)
+		go func() {
+			defer cancel()
+			if err := sendPasswordReset(ctx, db, mailer, baseURL, requestPayload.Email); err != nil {
+				logger.NewLogger().Error("Could not send password reset email", "error", err)
+			}
+		}()
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func sendPasswordReset(ctx context.Context, db *database.Queries, mailer mail.Mailer, baseURL, email string) error {
+	dbUser, err := db.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !dbUser.EmailVerifiedAt.Valid {
+		return nil
+	}
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	err = db.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    dbUser.ID,
+		ExpiresAt: time.Now().UTC().Add(passwordResetTokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := baseURL + "/app/reset-password?token=" + url.QueryEscape(token)
+	return mailer.Send(ctx, mail.Message{
+		To:      dbUser.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf(
+			"Someone asked to reset the password for your Chirpy account.\n\n"+
+				"Choose a new password by opening this link:\n\n%s\n\n"+
+				"The link expires in %d minutes and can only be used once. "+
+				"If you did not ask for this, you can ignore this email.\n",
+			link, int(passwordResetTokenTTL.Minutes()),
+		),
+	})
+}
+
+// passwordReset sets new passwords using tokens from reset emails.
+type passwordReset struct {
+	dbConn         *sql.DB
+	db             *database.Queries
+	denylist       auth.Denylist
+	hasher         auth.PasswordHasher
+	passwordPolicy password.Policy
+}
+
+// reset sets newPassword for the account token was sent to, and signs the
+// user out everywhere by revoking every refresh and access token. A bad
+// token is reported as errInvalidResetToken and a password the policy
+// rejects as a *passwordPolicyError; the token can be used again after
+// either.
+func (p *passwordReset) reset(ctx context.Context, token, newPassword string) error {
+	if token == "" {
+		return errInvalidResetToken
+	}
+
+	var userID uuid.UUID
+	err := p.db.InTx(ctx, p.dbConn, func(qtx *database.Queries) error {
+		var consumeErr error
+		userID, consumeErr = qtx.ConsumePasswordResetToken(ctx, auth.HashToken(token))
+		if errors.Is(consumeErr, sql.ErrNoRows) {
+			return errInvalidResetToken
+		}
+		if consumeErr != nil {
+			return consumeErr
+		}
+
+		// Checked here because the policy needs the account's email, and
+		// before hashing so rejected passwords cost no hash. Failing rolls
+		// back, so the token can be used again.
+		dbUser, getErr := qtx.GetUser(ctx, userID)
+		if getErr != nil {
+			return getErr
+		}
+		if violations := p.passwordPolicy.Check(newPassword, dbUser.Email); len(violations) > 0 {
+			return &passwordPolicyError{violations: violations}
+		}
+
+		hashedPassword, hashErr := p.hasher.Hash(newPassword)
+		if hashErr != nil {
+			return hashErr
+		}
+		return resetPassword(ctx, qtx, userID, hashedPassword)
+	})
+	if err != nil {
+		return err
+	}
+
+	return p.denylist.RevokeUserTokens(ctx, userID, time.Now())
+}
+
+// ResetPasswordHandler sets a new password using a token from a reset
+// email. It also signs the user out everywhere by revoking every refresh
+// and access token, and invalidates any other outstanding reset tokens.
+func ResetPasswordHandler(
+	dbConn *sql.DB,
+	db *database.Queries,
+	denylist auth.Denylist,
+	hasher auth.PasswordHasher,
+	passwordPolicy password.Policy,
+) http.HandlerFunc {
+	type RequestPayload struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	passwordReset := &passwordReset{dbConn, db, denylist, hasher, passwordPolicy}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestPayload RequestPayload
+		if err := json.NewDecoder(r.Body).Decode(&requestPayload); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+		if requestPayload.Token == "" || requestPayload.Password == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "token and password are required", nil)
+			return
+		}
+
+		err := passwordReset.reset(r.Context(), requestPayload.Token, requestPayload.Password)
+		if err != nil {
+			if errors.Is(err, errInvalidResetToken) {
+				utils.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+				return
+			}
+			var policyErr *passwordPolicyError
+			if errors.As(err, &policyErr) {
+				respondWithPasswordViolations(w, policyErr.violations)
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not reset password", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ResetPasswordPageHandler serves the page that reset emails link to, where
+// the user chooses a new password. Submitting it works like
+// POST /api/password/reset.
+func ResetPasswordPageHandler(
+	dbConn *sql.DB,
+	db *database.Queries,
+	denylist auth.Denylist,
+	hasher auth.PasswordHasher,
+	passwordPolicy password.Policy,
+) http.HandlerFunc {
+	page := template.Must(template.New("reset_password").Parse(resetPasswordTemplate))
+	passwordReset := &passwordReset{dbConn, db, denylist, hasher, passwordPolicy}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			renderHTML(w, page, http.StatusOK, resetPasswordPage{Token: r.URL.Query().Get("token")})
+			return
+		}
+
+		data := resetPasswordPage{Token: r.PostFormValue("token")}
+		newPassword := r.PostFormValue("password")
+		if newPassword == "" || newPassword != r.PostFormValue("confirm_password") {
+			data.Error = "Enter the same new password twice."
+			renderHTML(w, page, http.StatusBadRequest, data)
+			return
+		}
+
+		err := passwordReset.reset(r.Context(), data.Token, newPassword)
+		var policyErr *passwordPolicyError
+		switch {
+		case errors.Is(err, errInvalidResetToken):
+			renderHTML(w, page, http.StatusBadRequest, resetPasswordPage{
+				Error: "This link is invalid, has expired or was already used. Ask for a new one.",
+			})
+		case errors.As(err, &policyErr):
+			data.Error = "Password does not meet the requirements."
+			data.Violations = policyErr.violations
+			renderHTML(w, page, http.StatusBadRequest, data)
+		case err != nil:
+			logger.NewLogger().Error("Could not reset password", "error", err)
+			data.Error = "Something went wrong. Please try again later."
+			renderHTML(w, page, http.StatusInternalServerError, data)
+		default:
+			renderHTML(w, page, http.StatusOK, resetPasswordPage{
+				Done: "Your password has been changed, and you have been signed out everywhere. " +
+					"Sign in again with your new password.",
+			})
+		}
+	}
+}
+
+func resetPassword(ctx context.Context, qtx *database.Queries, userID uuid.UUID, hashedPassword string) error {
+	err := qtx.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		HashedPassword: hashedPassword,
+		ID:             userID,
+	})
+	if err != nil {
+		return err
+	}
+	if err = qtx.RevokeAllRefreshTokensForUser(ctx, userID); err != nil {
+		return err
+	}
+	if err = qtx.DeleteUserPersonalAccessTokens(ctx, userID); err != nil {
+		return err
+	}
+	return qtx.DeletePasswordResetTokensForUser(ctx, userID)
+}
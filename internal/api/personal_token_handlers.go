@@ -0,0 +1,182 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+const maxTokenNameLength = 100
+
+var (
+	errInvalidTokenName  = fmt.Errorf("name must be 1 to %d characters", maxTokenNameLength)
+	errTokenExpiryPassed = errors.New("expires_at must be in the future")
+)
+
+// PersonalAccessToken is a long-lived token for scripts and bots, sent as a
+// bearer token in place of an access token. It only grants its scopes.
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// Token is only sent when the token is created. Chirpy keeps a hash,
+	// so it cannot be shown again.
+	Token string `json:"token,omitempty"`
+}
+
+func personalAccessTokenFromDB(dbToken database.PersonalAccessToken) PersonalAccessToken {
+	token := PersonalAccessToken{
+		ID:        dbToken.ID,
+		CreatedAt: dbToken.CreatedAt,
+		Name:      dbToken.Name,
+		Scopes:    dbToken.Scopes,
+	}
+	if dbToken.ExpiresAt.Valid {
+		token.ExpiresAt = &dbToken.ExpiresAt.Time
+	}
+	if dbToken.LastUsedAt.Valid {
+		token.LastUsedAt = &dbToken.LastUsedAt.Time
+	}
+	return token
+}
+
+// CreatePersonalTokenHandler creates a personal access token for the
+// caller with the scopes they choose. Tokens without expires_at last until
+// they are deleted or the user signs out everywhere.
+func CreatePersonalTokenHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	type RequestPayload struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := authenticate(r, keys)
+		if err != nil {
+			respondAuthError(w, "", err)
+			return
+		}
+
+		var requestPayload RequestPayload
+		if err = json.NewDecoder(r.Body).Decode(&requestPayload); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		name := strings.TrimSpace(requestPayload.Name)
+		if name == "" || utf8.RuneCountInString(name) > maxTokenNameLength {
+			utils.RespondWithError(w, http.StatusBadRequest, errInvalidTokenName.Error(), errInvalidTokenName)
+			return
+		}
+		scopes, err := auth.ParseScope(strings.Join(requestPayload.Scopes, " "))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		var expiresAt sql.NullTime
+		if requestPayload.ExpiresAt != nil {
+			if !requestPayload.ExpiresAt.After(time.Now()) {
+				utils.RespondWithError(w, http.StatusBadRequest, errTokenExpiryPassed.Error(), errTokenExpiryPassed)
+				return
+			}
+			expiresAt = sql.NullTime{Time: requestPayload.ExpiresAt.UTC(), Valid: true}
+		}
+
+		token, err := auth.MakePersonalToken()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not create token", err)
+			return
+		}
+
+		dbToken, err := db.CreatePersonalAccessToken(r.Context(), database.CreatePersonalAccessTokenParams{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Name:      name,
+			TokenHash: auth.HashToken(token),
+			Scopes:    scopes,
+			ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not create token", err)
+			return
+		}
+
+		created := personalAccessTokenFromDB(dbToken)
+		created.Token = token
+		utils.RespondWithJSON(w, http.StatusCreated, created)
+	}
+}
+
+// ListPersonalTokensHandler lists the caller's personal access tokens,
+// newest first, including expired ones.
+func ListPersonalTokensHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := authenticate(r, keys)
+		if err != nil {
+			respondAuthError(w, "", err)
+			return
+		}
+
+		dbTokens, err := db.ListPersonalAccessTokens(r.Context(), userID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not list tokens", err)
+			return
+		}
+
+		tokens := make([]PersonalAccessToken, len(dbTokens))
+		for i, dbToken := range dbTokens {
+			tokens[i] = personalAccessTokenFromDB(dbToken)
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, struct {
+			Tokens []PersonalAccessToken `json:"tokens"`
+		}{Tokens: tokens})
+	}
+}
+
+// RevokePersonalTokenHandler deletes one of the caller's personal access
+// tokens. It stops working immediately.
+func RevokePersonalTokenHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		userID, err := authenticate(r, keys)
+		if err != nil {
+			respondAuthError(w, "", err)
+			return
+		}
+
+		deleted, err := db.DeletePersonalAccessToken(r.Context(), database.DeletePersonalAccessTokenParams{
+			ID:     tokenID,
+			UserID: userID,
+		})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not revoke token", err)
+			return
+		}
+		if deleted == 0 {
+			utils.RespondWithError(w, http.StatusNotFound, "Token not found", nil)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
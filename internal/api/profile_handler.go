@@ -0,0 +1,64 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/chirptext"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+// Profile is what anyone can see about a user. It deliberately has no
+// email or other private account details.
+type Profile struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	Username       string    `json:"username"`
+	DisplayName    string    `json:"display_name"`
+	Bio            string    `json:"bio"`
+	AvatarURL      string    `json:"avatar_url"`
+	IsChirpyRed    bool      `json:"is_chirpy_red"`
+	ChirpCount     int32     `json:"chirp_count"`
+	FollowerCount  int32     `json:"follower_count"`
+	FollowingCount int32     `json:"following_count"`
+}
+
+// GetProfileHandler returns the public profile of the user whose username,
+// matched case-insensitively, is in the path.
+func GetProfileHandler(db *database.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := r.PathValue("username")
+		if !chirptext.IsHandle(username) {
+			utils.RespondWithError(w, http.StatusNotFound, "User not found", nil)
+			return
+		}
+
+		row, err := db.GetUserProfile(r.Context(), username)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				utils.RespondWithError(w, http.StatusNotFound, "User not found", err)
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not load profile", err)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, Profile{
+			ID:             row.ID,
+			CreatedAt:      row.CreatedAt,
+			Username:       row.Username.String,
+			DisplayName:    row.DisplayName,
+			Bio:            row.Bio,
+			AvatarURL:      row.AvatarUrl,
+			IsChirpyRed:    row.IsChirpyRed,
+			ChirpCount:     row.ChirpCount,
+			FollowerCount:  row.FollowerCount,
+			FollowingCount: row.FollowingCount,
+		})
+	}
+}
@@ -2,16 +2,37 @@ package api
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/Myles-J/chirpy/internal/auth"
 	"github.com/Myles-J/chirpy/internal/database"
 	"github.com/Myles-J/chirpy/internal/utils"
 )
 
-func RefreshHandler(db *database.Queries, jwtSecret string) http.HandlerFunc {
+const (
+	// AccessTokenTTL is how long an access token is valid for.
+	AccessTokenTTL  = time.Hour
+	refreshTokenTTL = 60 * 24 * time.Hour
+)
+
+var (
+	errInvalidRefreshToken = errors.New("invalid refresh token")
+	errRefreshTokenReused  = errors.New("refresh token was already used")
+)
+
+// RefreshHandler exchanges a refresh token for a new access token and a new
+// refresh token. Every refresh token is single-use: the presented token is
+// revoked and replaced by one in the same family. Presenting a token that was
+// already replaced means it was copied, so the whole family is revoked and
+// both the thief and the real client must log in again. The new token keeps
+// the session's login details so the session list stays stable. Refresh
+// tokens issued to OAuth clients are only accepted at POST /oauth/token.
+func RefreshHandler(dbConn *sql.DB, db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		refreshToken, err := auth.GetBearerToken(r.Header)
 		if err != nil {
@@ -19,29 +40,96 @@ func RefreshHandler(db *database.Queries, jwtSecret string) http.HandlerFunc {
 			return
 		}
 
-		user, err := db.GetUserFromRefreshToken(context.Background(), refreshToken)
+		newRefreshToken, err := auth.MakeRefreshToken()
 		if err != nil {
-			utils.RespondWithError(w, http.StatusUnauthorized, "Couldn't get user for refresh token", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Couldn't create refresh token", err)
 			return
 		}
 
-		accessToken, err := auth.MakeJWT(user.ID, jwtSecret, time.Hour)
+		current, err := rotateRefreshToken(r.Context(), dbConn, db, refreshToken, newRefreshToken, uuid.NullUUID{})
 		if err != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, "Couldn't validate token", err)
+			if errors.Is(err, errInvalidRefreshToken) || errors.Is(err, errRefreshTokenReused) {
+				utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Couldn't refresh session", err)
 			return
 		}
 
-		if user.Token != refreshToken {
-			utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", errors.New("invalid refresh token"))
+		accessToken, err := keys.MakeJWT(current.UserID, AccessTokenTTL)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Couldn't validate token", err)
 			return
 		}
 
 		type response struct {
-			Token string `json:"token"`
+			Token        string `json:"token"`
+			RefreshToken string `json:"refresh_token"`
 		}
 
 		utils.RespondWithJSON(w, http.StatusOK, response{
-			Token: accessToken,
+			Token:        accessToken,
+			RefreshToken: newRefreshToken,
+		})
+	}
+}
+
+// rotateRefreshToken replaces refreshToken with newRefreshToken in the same
+// family and returns the row it replaced. The token must have been issued to
+// clientID, or to Chirpy itself when clientID is null, so OAuth clients and
+// first-party apps cannot redeem each other's tokens. The new token keeps
+// the client, scopes and login details of the old one.
+func rotateRefreshToken(
+	ctx context.Context,
+	dbConn *sql.DB,
+	db *database.Queries,
+	refreshToken, newRefreshToken string,
+	clientID uuid.NullUUID,
+) (database.RefreshToken, error) {
+	var (
+		current database.RefreshToken
+		reused  bool
+	)
+	err := db.InTx(ctx, dbConn, func(qtx *database.Queries) error {
+		var getErr error
+		current, getErr = qtx.GetRefreshTokenForUpdate(ctx, auth.HashToken(refreshToken))
+		if errors.Is(getErr, sql.ErrNoRows) {
+			return errInvalidRefreshToken
+		}
+		if getErr != nil {
+			return getErr
+		}
+
+		// The family revocation must commit, so reuse is reported
+		// outside the transaction instead of as an error.
+		if current.ReplacedBy.Valid {
+			reused = true
+			return qtx.RevokeRefreshTokenFamily(ctx, current.FamilyID)
+		}
+		if current.RevokedAt.Valid || !current.ExpiresAt.After(time.Now().UTC()) || current.ClientID != clientID {
+			return errInvalidRefreshToken
+		}
+
+		_, createErr := qtx.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+			TokenHash: auth.HashToken(newRefreshToken),
+			UserID:    current.UserID,
+			ExpiresAt: time.Now().UTC().Add(refreshTokenTTL),
+			FamilyID:  current.FamilyID,
+			UserAgent: current.UserAgent,
+			IpAddress: current.IpAddress,
+			ClientID:  current.ClientID,
+			Scopes:    current.Scopes,
+		})
+		if createErr != nil {
+			return createErr
+		}
+		return qtx.ReplaceRefreshToken(ctx, database.ReplaceRefreshTokenParams{
+			ReplacedBy: sql.NullString{String: auth.HashToken(newRefreshToken), Valid: true},
+			TokenHash:  current.TokenHash,
 		})
+	})
+	if err == nil && reused {
+		err = errRefreshTokenReused
 	}
+	return current, err
 }
@@ -1,7 +1,9 @@
 package api
 
 import (
-	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 
 	"github.com/Myles-J/chirpy/internal/auth"
@@ -9,7 +11,15 @@ import (
 	"github.com/Myles-J/chirpy/internal/utils"
 )
 
-func RevokeHandler(db *database.Queries) http.HandlerFunc {
+// RevokeHandler logs out the session of the refresh token in the
+// Authorization header. If the body carries the session's current access
+// token, that token is denylisted too instead of staying valid until it
+// expires.
+func RevokeHandler(db *database.Queries, keys *auth.Keyring, denylist auth.Denylist) http.HandlerFunc {
+	type RequestPayload struct {
+		AccessToken string `json:"access_token"`
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		refreshToken, err := auth.GetBearerToken(r.Header)
 		if err != nil {
@@ -17,12 +27,29 @@ func RevokeHandler(db *database.Queries) http.HandlerFunc {
 			return
 		}
 
-		err = db.RevokeRefreshToken(context.Background(), refreshToken)
+		var requestPayload RequestPayload
+		if err = json.NewDecoder(r.Body).Decode(&requestPayload); err != nil && !errors.Is(err, io.EOF) {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		err = db.RevokeRefreshToken(r.Context(), auth.HashToken(refreshToken))
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, "Couldn't revoke session", err)
 			return
 		}
 
+		// An access token that no longer verifies has nothing left to revoke.
+		if requestPayload.AccessToken != "" {
+			claims, parseErr := keys.ParseAccessToken(r.Context(), requestPayload.AccessToken)
+			if parseErr == nil && claims.ID != "" && claims.ExpiresAt != nil {
+				if err = denylist.RevokeToken(r.Context(), claims.ID, claims.ExpiresAt.Time); err != nil {
+					utils.RespondWithError(w, http.StatusInternalServerError, "Couldn't revoke access token", err)
+					return
+				}
+			}
+		}
+
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
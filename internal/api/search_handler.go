@@ -0,0 +1,97 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/pagination"
+	"github.com/Myles-J/chirpy/internal/search"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+// SearchResult is a chirp matching a search, with its relevance and a copy of
+// the body as escaped HTML where matching terms are wrapped in <mark> tags.
+type SearchResult struct {
+	Chirp
+
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type SearchPage struct {
+	Results    []SearchResult `json:"results"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// SearchChirpsHandler runs a search over chirps, most relevant first. The q
+// parameter accepts the query language understood by search.Parse.
+func SearchChirpsHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		viewerID := viewer(r, keys)
+
+		query := r.URL.Query()
+		searchQuery, err := search.Parse(query.Get("q"))
+		if err != nil {
+			var syntaxErr *search.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				utils.RespondWithError(w, http.StatusBadRequest, "Invalid search query: "+syntaxErr.Error(), err)
+				return
+			}
+			utils.RespondWithError(w, http.StatusBadRequest, "Missing search query", err)
+			return
+		}
+
+		limit, err := pagination.ParseLimit(query.Get("limit"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+
+		var offset int32
+		if cursorStr := query.Get("cursor"); cursorStr != "" {
+			offset, err = pagination.DecodeOffset(cursorStr)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+				return
+			}
+		}
+		if offset > math.MaxInt32-limit-1 {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor", pagination.ErrInvalidCursor)
+			return
+		}
+
+		rows, err := db.SearchChirps(r.Context(), searchQuery.Compile(), limit+1, offset)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not search chirps", err)
+			return
+		}
+
+		page := SearchPage{Results: make([]SearchResult, 0, len(rows))}
+		if len(rows) > int(limit) {
+			rows = rows[:limit]
+			page.NextCursor = pagination.EncodeOffset(offset + limit)
+		}
+
+		dbChirps := make([]database.Chirp, len(rows))
+		for i := range rows {
+			dbChirps[i] = rows[i].Chirp
+		}
+		chirps, err := presentChirps(r.Context(), db, viewerID, dbChirps)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not search chirps", err)
+			return
+		}
+		for i := range rows {
+			page.Results = append(page.Results, SearchResult{
+				Chirp:   chirps[i],
+				Rank:    rows[i].Rank,
+				Snippet: rows[i].Snippet,
+			})
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, page)
+	}
+}
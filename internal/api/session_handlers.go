@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+// maxUserAgentLength caps how much of a client's User-Agent is stored.
+const maxUserAgentLength = 512
+
+// Session is a login on one device: a chain of refresh tokens that started
+// with a single login. ID is the refresh token family, never a token itself.
+// Sessions granted to an OAuth client name the client; revoking one takes
+// the client's access away.
+type Session struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	ClientID   *uuid.UUID `json:"client_id,omitempty"`
+	ClientName string     `json:"client_name,omitempty"`
+}
+
+// ListSessionsHandler lists the caller's active sessions, most recently
+// used first.
+func ListSessionsHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := authenticate(r, keys)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+			return
+		}
+
+		rows, err := db.ListSessions(r.Context(), userID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not list sessions", err)
+			return
+		}
+
+		sessions := make([]Session, len(rows))
+		for i, row := range rows {
+			sessions[i] = Session{
+				ID:         row.FamilyID,
+				CreatedAt:  row.CreatedAt,
+				LastUsedAt: row.LastUsedAt,
+				ExpiresAt:  row.ExpiresAt,
+				UserAgent:  row.UserAgent,
+				IPAddress:  row.IpAddress,
+				ClientName: row.ClientName.String,
+			}
+			if row.ClientID.Valid {
+				sessions[i].ClientID = &row.ClientID.UUID
+			}
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, struct {
+			Sessions []Session `json:"sessions"`
+		}{Sessions: sessions})
+	}
+}
+
+// RevokeSessionHandler logs the caller out of one session. Access tokens
+// already issued to it stay valid until they expire.
+func RevokeSessionHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		userID, err := authenticate(r, keys)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+			return
+		}
+
+		revoked, err := db.RevokeSession(r.Context(), database.RevokeSessionParams{
+			FamilyID: sessionID,
+			UserID:   userID,
+		})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not revoke session", err)
+			return
+		}
+		if revoked == 0 {
+			utils.RespondWithError(w, http.StatusNotFound, "Session not found", nil)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RevokeAllSessionsHandler logs the caller out everywhere, including the
+// access token used to make the request.
+func RevokeAllSessionsHandler(db *database.Queries, keys *auth.Keyring, denylist auth.Denylist) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := authenticate(r, keys)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+			return
+		}
+
+		if err = signOutEverywhere(r.Context(), db, denylist, userID); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not revoke sessions", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// signOutEverywhere revokes every refresh token and personal access token
+// the user holds and every access token issued to them so far.
+func signOutEverywhere(ctx context.Context, db *database.Queries, denylist auth.Denylist, userID uuid.UUID) error {
+	if err := db.RevokeAllRefreshTokensForUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := db.DeleteUserPersonalAccessTokens(ctx, userID); err != nil {
+		return err
+	}
+	return denylist.RevokeUserTokens(ctx, userID, time.Now())
+}
+
+// clientIP returns the address of the peer that sent r. Chirpy is served
+// directly rather than behind a proxy, so forwarding headers, which clients
+// can forge, are ignored.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// clientUserAgent returns r's User-Agent, truncated and made valid UTF-8
+// for storage.
+func clientUserAgent(r *http.Request) string {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return strings.ToValidUTF8(userAgent, "")
+}
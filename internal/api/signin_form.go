@@ -0,0 +1,134 @@
+package api
+
+import (
+	"errors"
+	"html/template"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/logger"
+)
+
+// signInFormError is why a sign-in form on a server-rendered page was
+// refused, with the status and message to show it with.
+type signInFormError struct {
+	status  int
+	message string
+	// wait is how long a throttled client must wait, or zero.
+	wait time.Duration
+	// err is the underlying error, logged for server errors.
+	err error
+}
+
+func (e *signInFormError) Error() string {
+	return e.message
+}
+
+func (e *signInFormError) Unwrap() error {
+	return e.err
+}
+
+// checkForm signs in a user from a form with email, password and, for
+// accounts with two-factor authentication, code fields. Failures are
+// counted by the login throttle as at POST /api/login, and every error is a
+// *signInFormError ready to show on the page.
+func (l *passwordLogin) checkForm(r *http.Request) (database.User, error) {
+	email := r.PostFormValue("email")
+	ip := clientIP(r)
+	serverError := func(err error) error {
+		return &signInFormError{
+			status:  http.StatusInternalServerError,
+			message: "Something went wrong. Please try again later.",
+			err:     err,
+		}
+	}
+
+	dbUser, err := l.check(r.Context(), email, r.PostFormValue("password"), ip)
+	var throttled *loginThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		return dbUser, &signInFormError{
+			status:  http.StatusTooManyRequests,
+			message: "Too many failed sign-in attempts. Please try again later.",
+			wait:    throttled.wait,
+		}
+	case errors.Is(err, errInvalidCredentials):
+		return dbUser, &signInFormError{status: http.StatusUnauthorized, message: "Invalid email or password."}
+	case err != nil:
+		return dbUser, serverError(err)
+	}
+
+	if dbUser.SuspendedAt.Valid {
+		return dbUser, &signInFormError{status: http.StatusForbidden, message: "Your account is suspended."}
+	}
+
+	twoFactor, err := l.db.IsTwoFactorEnabled(r.Context(), dbUser.ID)
+	if err != nil {
+		return dbUser, serverError(err)
+	}
+	if twoFactor {
+		code := r.PostFormValue("code")
+		if strings.TrimSpace(code) == "" {
+			return dbUser, &signInFormError{
+				status:  http.StatusUnauthorized,
+				message: "Enter the code from your authenticator app or a recovery code.",
+			}
+		}
+		err = checkSecondFactor(r.Context(), l.db, l.loginThrottle, dbUser, code, ip)
+		switch {
+		case errors.As(err, &throttled):
+			return dbUser, &signInFormError{
+				status:  http.StatusTooManyRequests,
+				message: "Too many failed sign-in attempts. Please try again later.",
+				wait:    throttled.wait,
+			}
+		case errors.Is(err, errInvalidTwoFactorCode):
+			return dbUser, &signInFormError{status: http.StatusUnauthorized, message: "Invalid authentication code."}
+		case err != nil:
+			return dbUser, serverError(err)
+		}
+	}
+
+	if err = l.loginThrottle.RecordSuccess(r.Context(), email); err != nil {
+		return dbUser, serverError(err)
+	}
+	return dbUser, nil
+}
+
+// renderHTML responds with a server-rendered page. Pages that take
+// passwords must not be framed, or another site could trick users into
+// signing in or approving something through them.
+func renderHTML(w http.ResponseWriter, page *template.Template, status int, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+	w.WriteHeader(status)
+	if err := page.Execute(w, data); err != nil {
+		logger.NewLogger().Error("Could not render page", "page", page.Name(), "error", err)
+	}
+}
+
+// signInFailure returns the status and message to show for err, which
+// checkForm returned, and asks throttled clients to wait.
+func signInFailure(w http.ResponseWriter, err error) (int, string) {
+	var formErr *signInFormError
+	if !errors.As(err, &formErr) {
+		formErr = &signInFormError{
+			status:  http.StatusInternalServerError,
+			message: "Something went wrong. Please try again later.",
+			err:     err,
+		}
+	}
+	if formErr.status >= http.StatusInternalServerError {
+		logger.NewLogger().Error("Could not sign in", "error", formErr.err)
+	}
+	if formErr.wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(formErr.wait.Seconds()))))
+	}
+	return formErr.status, formErr.message
+}
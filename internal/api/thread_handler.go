@@ -0,0 +1,113 @@
+package api
+
+import (
+	"math"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/pagination"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+// ThreadReply is a chirp below the thread's focus. Depth is 1 for direct
+// replies; clients rebuild the tree from InReplyTo.
+type ThreadReply struct {
+	Chirp
+
+	Depth int32 `json:"depth"`
+}
+
+// Thread is the conversation around a chirp: the chain of chirps it replies
+// to (root first), the chirp itself, and a page of everything below it.
+type Thread struct {
+	Ancestors  []Chirp       `json:"ancestors"`
+	Chirp      Chirp         `json:"chirp"`
+	Replies    []ThreadReply `json:"replies"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// GetThreadHandler returns the thread around a chirp. Replies are ordered
+// breadth first, so each page holds shallower replies before deeper ones.
+func GetThreadHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		viewerID := viewer(r, keys)
+
+		chirpID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+
+		var offset int32
+		if cursorStr := r.URL.Query().Get("cursor"); cursorStr != "" {
+			offset, err = pagination.DecodeOffset(cursorStr)
+			if err != nil || offset > math.MaxInt32-limit-1 {
+				utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+				return
+			}
+		}
+
+		dbChirp, err := db.GetChirp(r.Context(), chirpID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusNotFound, "Chirp not found", err)
+			return
+		}
+
+		ancestors, err := db.ListChirpAncestors(r.Context(), chirpID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not load thread", err)
+			return
+		}
+
+		descendants, err := db.ListChirpDescendants(r.Context(), database.ListChirpDescendantsParams{
+			ChirpID:    chirpID,
+			PageLimit:  limit + 1,
+			PageOffset: offset,
+		})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not load thread", err)
+			return
+		}
+
+		hasMore := len(descendants) > int(limit)
+		if hasMore {
+			descendants = descendants[:limit]
+		}
+
+		// Present the whole thread at once so per-viewer fields cost one query.
+		dbChirps := make([]database.Chirp, 0, len(ancestors)+1+len(descendants))
+		dbChirps = append(dbChirps, ancestors...)
+		dbChirps = append(dbChirps, dbChirp)
+		for i := range descendants {
+			dbChirps = append(dbChirps, descendants[i].Chirp)
+		}
+		chirps, err := presentChirps(r.Context(), db, viewerID, dbChirps)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not load thread", err)
+			return
+		}
+
+		thread := Thread{
+			Ancestors: chirps[:len(ancestors)],
+			Chirp:     chirps[len(ancestors)],
+			Replies:   make([]ThreadReply, 0, len(descendants)),
+		}
+		if hasMore {
+			thread.NextCursor = pagination.EncodeOffset(offset + limit)
+		}
+		for i, reply := range chirps[len(ancestors)+1:] {
+			thread.Replies = append(thread.Replies, ThreadReply{Chirp: reply, Depth: descendants[i].Depth})
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, thread)
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/pagination"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+// TimelineHandler returns chirps from the accounts the caller follows, newest first.
+func TimelineHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := authorize(r, keys, auth.ScopeChirpsRead)
+		if err != nil {
+			respondAuthError(w, auth.ScopeChirpsRead, err)
+			return
+		}
+
+		limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+
+		cursorCreatedAt, cursorID, err := parseCursor(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+			return
+		}
+
+		dbChirps, err := db.ListTimeline(r.Context(), database.ListTimelineParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       limit + 1,
+		})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not load timeline", err)
+			return
+		}
+
+		page, err := newChirpPage(r.Context(), db, uuid.NullUUID{UUID: userID, Valid: true}, dbChirps, limit)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not load timeline", err)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, page)
+	}
+}
@@ -0,0 +1,314 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/totp"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+const (
+	twoFactorChallengeTTL = 5 * time.Minute
+	recoveryCodeCount     = 10
+	totpIssuer            = "Chirpy"
+)
+
+var (
+	errTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	errNoTwoFactorPending   = errors.New("two-factor setup has not been started")
+	errInvalidTwoFactorCode = errors.New("invalid two-factor code")
+)
+
+// TwoFactorChallenge is the login response for accounts with two-factor
+// authentication enabled.
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
+// TwoFactorSetup holds a new TOTP secret for the caller to add to an
+// authenticator app, either typed in or scanned from the URI as a QR code.
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// RecoveryCodes are shown once when two-factor authentication is enabled.
+// Each one can stand in for a TOTP code a single time.
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorSetupHandler starts TOTP enrolment for the caller. Nothing
+// changes at login until the secret is confirmed with a code from the app;
+// calling it again before then replaces the pending secret.
+func TwoFactorSetupHandler(db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := authenticate(r, keys)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+			return
+		}
+
+		dbUser, err := db.GetUser(r.Context(), userID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not start two-factor setup", err)
+			return
+		}
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not start two-factor setup", err)
+			return
+		}
+
+		started, err := db.StartTOTPEnrolment(r.Context(), database.StartTOTPEnrolmentParams{
+			UserID: userID,
+			Secret: secret,
+		})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not start two-factor setup", err)
+			return
+		}
+		if started == 0 {
+			utils.RespondWithError(w, http.StatusConflict, errTwoFactorEnabled.Error(), nil)
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, TwoFactorSetup{
+			Secret:     secret,
+			OTPAuthURI: totp.URI(secret, totpIssuer, dbUser.Email),
+		})
+	}
+}
+
+// TwoFactorConfirmHandler enables two-factor authentication once the caller
+// proves their app produces the right codes, and responds with a fresh set
+// of recovery codes.
+func TwoFactorConfirmHandler(dbConn *sql.DB, db *database.Queries, keys *auth.Keyring) http.HandlerFunc {
+	type RequestPayload struct {
+		Code string `json:"code"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := authenticate(r, keys)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+			return
+		}
+
+		var requestPayload RequestPayload
+		if err = json.NewDecoder(r.Body).Decode(&requestPayload); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		codes, err := auth.MakeRecoveryCodes(recoveryCodeCount)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not create recovery codes", err)
+			return
+		}
+		hashes := make([]string, len(codes))
+		for i, code := range codes {
+			hashes[i] = auth.HashRecoveryCode(code)
+		}
+
+		err = db.InTx(r.Context(), dbConn, func(qtx *database.Queries) error {
+			credential, getErr := qtx.GetTOTPCredential(r.Context(), userID)
+			if errors.Is(getErr, sql.ErrNoRows) {
+				return errNoTwoFactorPending
+			}
+			if getErr != nil {
+				return getErr
+			}
+			if credential.ConfirmedAt.Valid {
+				return errTwoFactorEnabled
+			}
+
+			step, verifyErr := totp.Verify(credential.Secret, requestPayload.Code, time.Now())
+			if verifyErr != nil {
+				return errInvalidTwoFactorCode
+			}
+
+			confirmed, confirmErr := qtx.ConfirmTOTP(r.Context(), database.ConfirmTOTPParams{
+				Step:   step,
+				UserID: userID,
+				Secret: credential.Secret,
+			})
+			if confirmErr != nil {
+				return confirmErr
+			}
+			if confirmed == 0 {
+				// Setup was restarted or finished by a concurrent request.
+				return errNoTwoFactorPending
+			}
+
+			if deleteErr := qtx.DeleteRecoveryCodes(r.Context(), userID); deleteErr != nil {
+				return deleteErr
+			}
+			return qtx.CreateRecoveryCodes(r.Context(), database.CreateRecoveryCodesParams{
+				UserID:     userID,
+				CodeHashes: hashes,
+			})
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, errInvalidTwoFactorCode):
+				utils.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+			case errors.Is(err, errNoTwoFactorPending), errors.Is(err, errTwoFactorEnabled):
+				utils.RespondWithError(w, http.StatusConflict, err.Error(), err)
+			default:
+				utils.RespondWithError(w, http.StatusInternalServerError, "Could not enable two-factor authentication", err)
+			}
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, RecoveryCodes{RecoveryCodes: codes})
+	}
+}
+
+// LoginTwoFactorHandler completes a login started at POST /api/login with
+// a code from the user's authenticator app or one of their recovery codes.
+// A challenge token is good for one attempt, so a mistyped code means
+// logging in with the password again. Wrong codes count towards the same
+// limits as wrong passwords.
+func LoginTwoFactorHandler(
+	db *database.Queries,
+	keys *auth.Keyring,
+	denylist auth.Denylist,
+	loginThrottle *LoginThrottle,
+) http.HandlerFunc {
+	type RequestPayload struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestPayload RequestPayload
+		if err := json.NewDecoder(r.Body).Decode(&requestPayload); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		claims, err := keys.ParseTwoFactorChallenge(r.Context(), requestPayload.ChallengeToken)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Invalid or expired challenge", err)
+			return
+		}
+		if err = denylist.RevokeToken(r.Context(), claims.ID, claims.ExpiresAt.Time); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not complete login", err)
+			return
+		}
+
+		userID, err := uuid.Parse(claims.Subject)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Invalid or expired challenge", err)
+			return
+		}
+
+		dbUser, err := db.GetUser(r.Context(), userID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not complete login", err)
+			return
+		}
+
+		err = checkSecondFactor(r.Context(), db, loginThrottle, dbUser, requestPayload.Code, clientIP(r))
+		var throttled *loginThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			respondLoginThrottled(w, throttled.wait)
+			return
+		case errors.Is(err, errInvalidTwoFactorCode):
+			utils.RespondWithError(w, http.StatusUnauthorized, "Invalid code", err)
+			return
+		case err != nil:
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not complete login", err)
+			return
+		}
+
+		if dbUser.SuspendedAt.Valid {
+			utils.RespondWithError(w, http.StatusForbidden, "Account suspended.", nil)
+			return
+		}
+
+		if err = loginThrottle.RecordSuccess(r.Context(), dbUser.Email); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not complete login", err)
+			return
+		}
+		issueSession(w, r, db, keys, dbUser)
+	}
+}
+
+// checkSecondFactor is verifySecondFactor for a login by dbUser from ip,
+// throttled like passwords. While the login is throttled it returns a
+// *loginThrottledError without checking code.
+func checkSecondFactor(
+	ctx context.Context,
+	db *database.Queries,
+	loginThrottle *LoginThrottle,
+	dbUser database.User,
+	code, ip string,
+) error {
+	wait, err := loginThrottle.Reserve(ctx, dbUser.Email, ip)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return &loginThrottledError{wait: wait}
+	}
+
+	if err = verifySecondFactor(ctx, db, dbUser.ID, code); err != nil {
+		return err
+	}
+	return loginThrottle.Release(ctx, dbUser.Email, ip)
+}
+
+// verifySecondFactor accepts a TOTP code newer than the last one used, or
+// a recovery code that has not been used yet. Either is spent on success.
+func verifySecondFactor(ctx context.Context, db *database.Queries, userID uuid.UUID, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) != totp.Digits {
+		used, err := db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashRecoveryCode(code),
+		})
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			return errInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	credential, err := db.GetTOTPCredential(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errInvalidTwoFactorCode
+	}
+	if err != nil {
+		return err
+	}
+	step, err := totp.Verify(credential.Secret, code, time.Now())
+	if err != nil {
+		return errInvalidTwoFactorCode
+	}
+
+	used, err := db.UseTOTPStep(ctx, database.UseTOTPStepParams{Step: step, UserID: userID})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return errInvalidTwoFactorCode
+	}
+	return nil
+}
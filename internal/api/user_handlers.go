@@ -1,30 +1,172 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/chirptext"
 	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/logger"
+	"github.com/Myles-J/chirpy/internal/password"
 	"github.com/Myles-J/chirpy/internal/utils"
 	"github.com/google/uuid"
 )
 
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxAvatarURLLength   = 2048
+)
+
+var (
+	errInvalidUsername = fmt.Errorf(
+		"username must be 1 to %d letters, digits or underscores", chirptext.MaxHandleLength,
+	)
+	errDisplayNameTooLong = fmt.Errorf("display_name must be at most %d characters", maxDisplayNameLength)
+	errBioTooLong         = fmt.Errorf("bio must be at most %d characters", maxBioLength)
+	errInvalidAvatarURL   = errors.New("avatar_url must be an http or https URL")
+	errInvalidEmail       = errors.New("email must be a valid email address")
+)
+
 type User struct {
-	ID          uuid.UUID `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Email       string    `json:"email"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	Username      *string   `json:"username"`
+	DisplayName   string    `json:"display_name"`
+	Bio           string    `json:"bio"`
+	AvatarURL     string    `json:"avatar_url"`
+	EmailVerified bool      `json:"email_verified"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
 }
 
+// requestParams is the body of a create or update request. On update, an
+// empty email or password and any omitted profile field are left unchanged.
 type requestParams struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	profileParams
+}
+
+// profileParams are the public profile fields a user can set. An empty
+// display_name, bio or avatar_url clears it.
+type profileParams struct {
+	Username    *string `json:"username"`
+	DisplayName *string `json:"display_name"`
+	Bio         *string `json:"bio"`
+	AvatarURL   *string `json:"avatar_url"`
+}
+
+// profileFields are validated profileParams. Fields the request left out are null.
+type profileFields struct {
+	Username    sql.NullString
+	DisplayName sql.NullString
+	Bio         sql.NullString
+	AvatarURL   sql.NullString
+}
+
+func (p profileParams) validate() (profileFields, error) {
+	var fields profileFields
+
+	if p.Username != nil {
+		if !chirptext.IsHandle(*p.Username) {
+			return profileFields{}, errInvalidUsername
+		}
+		fields.Username = sql.NullString{String: *p.Username, Valid: true}
+	}
+
+	if p.DisplayName != nil {
+		displayName := strings.TrimSpace(*p.DisplayName)
+		if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+			return profileFields{}, errDisplayNameTooLong
+		}
+		fields.DisplayName = sql.NullString{String: displayName, Valid: true}
+	}
+
+	if p.Bio != nil {
+		bio := strings.TrimSpace(*p.Bio)
+		if utf8.RuneCountInString(bio) > maxBioLength {
+			return profileFields{}, errBioTooLong
+		}
+		fields.Bio = sql.NullString{String: bio, Valid: true}
+	}
+
+	if p.AvatarURL != nil {
+		avatarURL := strings.TrimSpace(*p.AvatarURL)
+		if avatarURL != "" && !isWebURL(avatarURL) {
+			return profileFields{}, errInvalidAvatarURL
+		}
+		fields.AvatarURL = sql.NullString{String: avatarURL, Valid: true}
+	}
+
+	return fields, nil
+}
+
+// isEmailAddress reports whether s is a bare email address, without a
+// display name, comments or surrounding space.
+func isEmailAddress(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
 }
 
-func CreateUserHandler(db *database.Queries) http.HandlerFunc {
+func isWebURL(s string) bool {
+	if len(s) > maxAvatarURLLength {
+		return false
+	}
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// respondWithUserWriteError reports a failed insert or update of a user,
+// telling the client which unique field clashed with another account.
+func respondWithUserWriteError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case database.IsUniqueViolationOf(err, "users_username_lower_idx"):
+		utils.RespondWithError(w, http.StatusConflict, "Username is already taken", err)
+	case database.IsUniqueViolationOf(err, "users_email_key"):
+		utils.RespondWithError(w, http.StatusConflict, "Email is already registered", err)
+	default:
+		utils.RespondWithError(w, http.StatusInternalServerError, msg, err)
+	}
+}
+
+func userFromDB(dbUser database.User) User {
+	user := User{
+		ID:            dbUser.ID,
+		CreatedAt:     dbUser.CreatedAt,
+		UpdatedAt:     dbUser.UpdatedAt,
+		Email:         dbUser.Email,
+		DisplayName:   dbUser.DisplayName,
+		Bio:           dbUser.Bio,
+		AvatarURL:     dbUser.AvatarUrl,
+		EmailVerified: dbUser.EmailVerifiedAt.Valid,
+		IsChirpyRed:   dbUser.IsChirpyRed,
+	}
+	if dbUser.Username.Valid {
+		user.Username = &dbUser.Username.String
+	}
+	return user
+}
+
+// CreateUserHandler signs up a new user and emails them a link to verify
+// their address. The account is created even if that email cannot be sent;
+// the user can ask for another one.
+func CreateUserHandler(
+	db *database.Queries,
+	verifier *EmailVerifier,
+	hasher auth.PasswordHasher,
+	passwordPolicy password.Policy,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var requestParams requestParams
 
@@ -34,7 +176,23 @@ func CreateUserHandler(db *database.Queries) http.HandlerFunc {
 			return
 		}
 
-		hashedPassword, err := auth.HashPassword(requestParams.Password)
+		if !isEmailAddress(requestParams.Email) {
+			utils.RespondWithError(w, http.StatusBadRequest, errInvalidEmail.Error(), errInvalidEmail)
+			return
+		}
+
+		profile, err := requestParams.validate()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+
+		if violations := passwordPolicy.Check(requestParams.Password, requestParams.Email); len(violations) > 0 {
+			respondWithPasswordViolations(w, violations)
+			return
+		}
+
+		hashedPassword, err := hasher.Hash(requestParams.Password)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, "Could not hash password", err)
 			return
@@ -43,33 +201,41 @@ func CreateUserHandler(db *database.Queries) http.HandlerFunc {
 		dbUser, err := db.CreateUser(r.Context(), database.CreateUserParams{
 			Email:          requestParams.Email,
 			HashedPassword: hashedPassword,
+			Username:       profile.Username,
+			DisplayName:    profile.DisplayName.String,
+			Bio:            profile.Bio.String,
+			AvatarUrl:      profile.AvatarURL.String,
 		})
 		if err != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, "Could not create user", err)
+			respondWithUserWriteError(w, "Could not create user", err)
 			return
 		}
 
-		utils.RespondWithJSON(w, http.StatusCreated, User{
-			ID:          dbUser.ID,
-			CreatedAt:   dbUser.CreatedAt,
-			UpdatedAt:   dbUser.UpdatedAt,
-			Email:       dbUser.Email,
-			IsChirpyRed: dbUser.IsChirpyRed,
-		})
+		if err = verifier.SendWelcome(r.Context(), dbUser.ID, dbUser.Email); err != nil {
+			logger.NewLogger().Error("Could not send verification email", "error", err)
+		}
+
+		utils.RespondWithJSON(w, http.StatusCreated, userFromDB(dbUser))
 	}
 }
 
-func UpdateUserHandler(db *database.Queries, tokenSecret string) http.HandlerFunc {
+// UpdateUserHandler updates the caller's account. Changing the email
+// address marks it unverified and sends a new verification email. Changing
+// the password signs the user out everywhere, this session included. OAuth
+// clients with the profile scope can change everything but those two.
+func UpdateUserHandler(
+	db *database.Queries,
+	verifier *EmailVerifier,
+	keys *auth.Keyring,
+	denylist auth.Denylist,
+	hasher auth.PasswordHasher,
+	passwordPolicy password.Policy,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Authenticate user
-		token, err := auth.GetBearerToken(r.Header)
+		userID, err := authorize(r, keys, auth.ScopeProfile)
 		if err != nil {
-			utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
-			return
-		}
-		userID, err := auth.ValidateJWT(token, tokenSecret)
-		if err != nil {
-			utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+			respondAuthError(w, auth.ScopeProfile, err)
 			return
 		}
 
@@ -80,34 +246,83 @@ func UpdateUserHandler(db *database.Queries, tokenSecret string) http.HandlerFun
 			return
 		}
 
-		// Hash password only if provided
-		hashedPassword := ""
+		// OAuth clients may edit the profile but not the login details.
+		if params.Email != "" || params.Password != "" {
+			if _, err = authenticate(r, keys); err != nil {
+				respondAuthError(w, "", err)
+				return
+			}
+		}
+
+		if params.Email != "" && !isEmailAddress(params.Email) {
+			utils.RespondWithError(w, http.StatusBadRequest, errInvalidEmail.Error(), errInvalidEmail)
+			return
+		}
+
+		profile, err := params.validate()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+
+		// Check and hash password only if provided
+		var hashedPassword sql.NullString
 		if params.Password != "" {
-			var hashErr error
-			hashedPassword, hashErr = auth.HashPassword(params.Password)
+			email := params.Email
+			if email == "" {
+				current, getErr := db.GetUser(r.Context(), userID)
+				if getErr != nil {
+					utils.RespondWithError(w, http.StatusInternalServerError, "Could not update user", getErr)
+					return
+				}
+				email = current.Email
+			}
+			if violations := passwordPolicy.Check(params.Password, email); len(violations) > 0 {
+				respondWithPasswordViolations(w, violations)
+				return
+			}
+
+			hashed, hashErr := hasher.Hash(params.Password)
 			if hashErr != nil {
 				utils.RespondWithError(w, http.StatusInternalServerError, "Could not hash password", hashErr)
 				return
 			}
+			hashedPassword = sql.NullString{String: hashed, Valid: true}
 		}
 
 		// Update user
 		dbUser, err := db.UpdateUser(r.Context(), database.UpdateUserParams{
-			Email:          params.Email,
+			Email:          sql.NullString{String: params.Email, Valid: params.Email != ""},
 			HashedPassword: hashedPassword,
+			Username:       profile.Username,
+			DisplayName:    profile.DisplayName,
+			Bio:            profile.Bio,
+			AvatarUrl:      profile.AvatarURL,
 			ID:             userID,
 		})
 		if err != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, "Could not update user", err)
+			respondWithUserWriteError(w, "Could not update user", err)
 			return
 		}
 
-		utils.RespondWithJSON(w, http.StatusOK, User{
-			ID:          dbUser.ID,
-			CreatedAt:   dbUser.CreatedAt,
-			UpdatedAt:   dbUser.UpdatedAt,
-			Email:       dbUser.Email,
-			IsChirpyRed: dbUser.IsChirpyRed,
-		})
+		if hashedPassword.Valid {
+			if err = signOutEverywhere(r.Context(), db, denylist, userID); err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, "Could not revoke sessions", err)
+				return
+			}
+		}
+
+		if params.Email != "" && !dbUser.EmailVerifiedAt.Valid {
+			if err = verifier.SendEmailChange(r.Context(), dbUser.ID, dbUser.Email); err != nil {
+				logger.NewLogger().Error("Could not send verification email", "error", err)
+			}
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, userFromDB(dbUser))
 	}
 }
+
+// respondWithPasswordViolations reports every password rule a request broke.
+func respondWithPasswordViolations(w http.ResponseWriter, violations []password.Violation) {
+	utils.RespondWithErrorDetails(w, http.StatusBadRequest, "Password does not meet the requirements.", violations)
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+	"github.com/Myles-J/chirpy/internal/database"
+	"github.com/Myles-J/chirpy/internal/logger"
+	"github.com/Myles-J/chirpy/internal/mail"
+	"github.com/Myles-J/chirpy/internal/utils"
+)
+
+const verificationTokenTTL = 24 * time.Hour
+
+var errInvalidVerificationToken = errors.New("verification token is invalid, expired or already used")
+
+// verifyTemplate is the page at /app/verify that verification emails link to.
+//
+//go:embed templates/verify.html
+var verifyTemplate string
+
+// verifyPage is the data for verifyTemplate. Done replaces the form once the
+// address is verified.
+type verifyPage struct {
+	Token string
+	Error string
+	Done  string
+}
+
+// EmailVerifier emails users a single-use token proving they own their
+// email address. Only a hash of each token is stored. Sends are throttled
+// per user and per address, so an account cannot be used to flood an inbox.
+type EmailVerifier struct {
+	db       *database.Queries
+	throttle *RequestThrottle
+	mailer   mail.Mailer
+	baseURL  string
+}
+
+// NewEmailVerifier returns an EmailVerifier whose emails link to the web
+// app served at baseURL.
+func NewEmailVerifier(
+	db *database.Queries,
+	throttle *RequestThrottle,
+	mailer mail.Mailer,
+	baseURL string,
+) *EmailVerifier {
+	return &EmailVerifier{db: db, throttle: throttle, mailer: mailer, baseURL: baseURL}
+}
+
+// verificationThrottledError is returned instead of sending a verification
+// email while the user or address must wait for another.
+type verificationThrottledError struct {
+	wait time.Duration
+}
+
+func (e *verificationThrottledError) Error() string {
+	return fmt.Sprintf("too many verification emails, retry in %s", e.wait)
+}
+
+// verificationEmail is the wording of a verification email. The link and
+// its expiry go between intro and outro.
+type verificationEmail struct {
+	subject, intro, outro string
+}
+
+// Send issues a verification token for email and mails it there.
+func (v *EmailVerifier) Send(ctx context.Context, userID uuid.UUID, email string) error {
+	return v.send(ctx, userID, email, verificationEmail{
+		subject: "Verify your Chirpy email address",
+		intro:   "Confirm your email address by opening this link:",
+		outro:   "If you did not ask for this, you can ignore this email.",
+	})
+}
+
+// SendWelcome is Send for the first email of a new account.
+func (v *EmailVerifier) SendWelcome(ctx context.Context, userID uuid.UUID, email string) error {
+	return v.send(ctx, userID, email, verificationEmail{
+		subject: "Verify your Chirpy email address",
+		intro:   "Welcome to Chirpy!\n\nConfirm your email address by opening this link:",
+		outro:   "If you did not sign up, you can ignore this email.",
+	})
+}
+
+// SendEmailChange is Send for an address the user has just changed their
+// account's email to.
+func (v *EmailVerifier) SendEmailChange(ctx context.Context, userID uuid.UUID, email string) error {
+	return v.send(ctx, userID, email, verificationEmail{
+		subject: "Confirm your new Chirpy email address",
+		intro: "The email address of a Chirpy account was changed to this one.\n\n" +
+			"Confirm it by opening this link:",
+		outro: "If you did not change it, you can ignore this email.",
+	})
+}
+
+func (v *EmailVerifier) send(ctx context.Context, userID uuid.UUID, email string, message verificationEmail) error {
+	wait, err := v.throttle.Reserve(ctx, "user:"+userID.String(), "email:"+normalizeEmail(email))
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return &verificationThrottledError{wait: wait}
+	}
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	err = v.db.CreateVerificationToken(ctx, database.CreateVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: time.Now().UTC().Add(verificationTokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := v.baseURL + "/app/verify?token=" + url.QueryEscape(token)
+	return v.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: message.subject,
+		Body: fmt.Sprintf(
+			"%s\n\n%s\n\nThe link expires in %d hours. %s\n",
+			message.intro, link, int(verificationTokenTTL.Hours()), message.outro,
+		),
+	})
+}
+
+// VerifyEmailHandler consumes a verification token and marks the address it
+// was sent to as verified. A token only counts if that address is still the
+// account's email.
+func VerifyEmailHandler(dbConn *sql.DB, db *database.Queries) http.HandlerFunc {
+	type RequestPayload struct {
+		Token string `json:"token"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestPayload RequestPayload
+		if err := json.NewDecoder(r.Body).Decode(&requestPayload); err != nil || requestPayload.Token == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request", err)
+			return
+		}
+
+		err := verifyEmail(r.Context(), dbConn, db, requestPayload.Token)
+		if err != nil {
+			if errors.Is(err, errInvalidVerificationToken) {
+				utils.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not verify email", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// VerifyEmailPageHandler serves the page that verification emails link to.
+// GET only asks the user to confirm, since mail scanners open links too;
+// POST consumes the token like POST /api/users/verify.
+func VerifyEmailPageHandler(dbConn *sql.DB, db *database.Queries) http.HandlerFunc {
+	page := template.Must(template.New("verify").Parse(verifyTemplate))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			renderHTML(w, page, http.StatusOK, verifyPage{Token: r.URL.Query().Get("token")})
+			return
+		}
+
+		err := verifyEmail(r.Context(), dbConn, db, r.PostFormValue("token"))
+		if errors.Is(err, errInvalidVerificationToken) {
+			renderHTML(w, page, http.StatusBadRequest, verifyPage{
+				Error: "This link is invalid or has expired. Ask Chirpy to send you a new one.",
+			})
+			return
+		}
+		if err != nil {
+			logger.NewLogger().Error("Could not verify email", "error", err)
+			data := verifyPage{Token: r.PostFormValue("token"), Error: "Something went wrong. Please try again later."}
+			renderHTML(w, page, http.StatusInternalServerError, data)
+			return
+		}
+		renderHTML(w, page, http.StatusOK, verifyPage{Done: "Your email address is verified."})
+	}
+}
+
+// verifyEmail consumes a verification token and marks the address it was
+// sent to as verified, or returns errInvalidVerificationToken.
+func verifyEmail(ctx context.Context, dbConn *sql.DB, db *database.Queries, token string) error {
+	if token == "" {
+		return errInvalidVerificationToken
+	}
+	return db.InTx(ctx, dbConn, func(qtx *database.Queries) error {
+		row, consumeErr := qtx.ConsumeVerificationToken(ctx, auth.HashToken(token))
+		if errors.Is(consumeErr, sql.ErrNoRows) {
+			return errInvalidVerificationToken
+		}
+		if consumeErr != nil {
+			return consumeErr
+		}
+
+		verified, markErr := qtx.MarkEmailVerified(ctx, database.MarkEmailVerifiedParams{
+			ID:    row.UserID,
+			Email: row.Email,
+		})
+		if markErr != nil {
+			return markErr
+		}
+		if verified == 0 {
+			return errInvalidVerificationToken
+		}
+
+		return qtx.DeleteVerificationTokensForUser(ctx, row.UserID)
+	})
+}
+
+// ResendVerificationHandler emails the caller a fresh verification token,
+// unless one was sent to them or their address too recently.
+func ResendVerificationHandler(db *database.Queries, verifier *EmailVerifier, keys *auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := authenticate(r, keys)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+			return
+		}
+
+		dbUser, err := db.GetUser(r.Context(), userID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+			return
+		}
+		if dbUser.EmailVerifiedAt.Valid {
+			utils.RespondWithError(w, http.StatusConflict, "Email is already verified", nil)
+			return
+		}
+
+		err = verifier.Send(r.Context(), dbUser.ID, dbUser.Email)
+		var throttled *verificationThrottledError
+		if errors.As(err, &throttled) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.wait.Seconds()))))
+			utils.RespondWithError(
+				w,
+				http.StatusTooManyRequests,
+				"A verification email was sent recently. Please try again later.",
+				err,
+			)
+			return
+		}
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Could not send verification email", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
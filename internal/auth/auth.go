@@ -1,7 +1,9 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"net/http"
 	"strings"
@@ -12,64 +14,32 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 var ErrNoAuthHeaderIncluded = errors.New("no auth header included in request")
 
-func HashPassword(password string) (string, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
-	return string(hashed), err
+func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	return NewHMACKeyring(tokenSecret).MakeJWT(userID, expiresIn)
 }
 
-func CheckPassword(hash, password string) error {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err
+// ValidateJWT parses and validates a JWT, returning the userID if valid.
+func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	return NewHMACKeyring(tokenSecret).ValidateJWT(context.Background(), tokenString)
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+func accessClaims(userID uuid.UUID, expiresIn time.Duration) *jwt.RegisteredClaims {
 	now := time.Now()
-	claims := &jwt.RegisteredClaims{
+	return &jwt.RegisteredClaims{
 		ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
 		IssuedAt:  jwt.NewNumericDate(now),
 		Issuer:    "chirpy",
 		Subject:   userID.String(),
+		ID:        uuid.NewString(),
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(tokenSecret))
 }
 
-// ValidateJWT parses and validates a JWT, returning the userID if valid.
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	const leeway = 5 * time.Second
-	// We will parse the token into a struct that includes or is RegisteredClaims
-	// as that's what was used to create the token.
-	claims := &jwt.RegisteredClaims{}
-
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		// Return the secret key to validate the signature
-		return []byte(tokenSecret), nil
-	},
-		// Add leeway to account for potential clock drift
-		jwt.WithLeeway(leeway),
-	)
-
-	if err != nil {
-		// Return the specific error from parsing
-		return uuid.Nil, fmt.Errorf("failed to parse or validate JWT: %w", err)
-	}
-
-	// Check if the token is valid after parsing
-	if !token.Valid {
-		return uuid.Nil, errors.New("invalid JWT token")
-	}
-
-	// Extract the userID from the Subject claim
+// subjectUserID extracts the userID from the Subject claim.
+func subjectUserID(claims *jwt.RegisteredClaims) (uuid.UUID, error) {
 	userIDStr := claims.Subject
 	if userIDStr == "" {
 		return uuid.Nil, errors.New("JWT subject (userID) is missing or empty")
@@ -79,8 +49,6 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("failed to parse userID from JWT subject: %w", err)
 	}
-
-	// Token is valid and userID is extracted
 	return userID, nil
 }
 
@@ -122,3 +90,11 @@ func MakeRefreshToken() (string, error) {
 	}
 	return hex.EncodeToString(token), nil
 }
+
+// HashToken returns the hex encoded SHA-256 of a random token, so tokens can
+// be stored and looked up without keeping them in a usable form. A fast hash
+// is enough because tokens, unlike passwords, cannot be guessed.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
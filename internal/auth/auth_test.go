@@ -215,3 +215,47 @@ func TestMakeRefreshToken(t *testing.T) {
 		t.Errorf("Expected token length to be 64, but got %d", len(token))
 	}
 }
+
+func TestHashToken(t *testing.T) {
+	// echo -n "abc" | sha256sum
+	const want = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got := auth.HashToken("abc"); got != want {
+		t.Errorf("Expected hash %s, but got %s", want, got)
+	}
+	if auth.HashToken("abc") == auth.HashToken("abd") {
+		t.Error("Expected different tokens to have different hashes")
+	}
+}
+
+func TestMakeRecoveryCodes(t *testing.T) {
+	codes, err := auth.MakeRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("Failed to make recovery codes: %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("Expected 10 codes, but got %d", len(codes))
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 14 || strings.Count(code, "-") != 2 {
+			t.Errorf("Expected a code like abcd-efgh-ijkl, but got %q", code)
+		}
+		if seen[code] {
+			t.Errorf("Expected unique codes, but got %q twice", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	want := auth.HashRecoveryCode("abcd-efgh-ijkl")
+	for _, typed := range []string{"ABCD-EFGH-IJKL", "abcdefghijkl", "abcd efgh ijkl"} {
+		if got := auth.HashRecoveryCode(typed); got != want {
+			t.Errorf("Expected %q to hash like abcd-efgh-ijkl", typed)
+		}
+	}
+	if auth.HashRecoveryCode("abcd-efgh-ijkm") == want {
+		t.Error("Expected different codes to have different hashes")
+	}
+}
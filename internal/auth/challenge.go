@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// twoFactorAudience marks tokens showing that a user's password was
+// checked but their second factor still has to be. They cannot be used as
+// access tokens.
+const twoFactorAudience = "chirpy-2fa"
+
+// MakeTwoFactorChallenge issues a challenge token for userID.
+func (k *Keyring) MakeTwoFactorChallenge(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	claims := accessClaims(userID, expiresIn)
+	claims.Audience = jwt.ClaimStrings{twoFactorAudience}
+	return k.Sign(claims)
+}
+
+// ParseTwoFactorChallenge verifies a token from MakeTwoFactorChallenge and
+// returns its claims. Revoke its jti once it has been used.
+func (k *Keyring) ParseTwoFactorChallenge(ctx context.Context, tokenString string) (*jwt.RegisteredClaims, error) {
+	claims, err := k.parseFor(ctx, tokenString, twoFactorAudience)
+	if err != nil {
+		return nil, err
+	}
+	return &claims.RegisteredClaims, nil
+}
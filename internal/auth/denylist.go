@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrTokenRevoked = errors.New("access token has been revoked")
+
+// Denylist records access tokens revoked before they expire, either one at
+// a time by jti or all of a user's tokens issued before a cutoff. Token
+// issue times have one second resolution, so cutoffs are truncated to the
+// second and a token issued in the same second as a cutoff is still valid.
+type Denylist interface {
+	// RevokeToken rejects the token with jti until expiresAt.
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	// RevokeUserTokens rejects every token for userID issued before notBefore.
+	RevokeUserTokens(ctx context.Context, userID uuid.UUID, notBefore time.Time) error
+	// IsRevoked reports whether either rule rejects a token.
+	IsRevoked(ctx context.Context, jti string, userID uuid.UUID, issuedAt time.Time) (bool, error)
+}
+
+// MemoryDenylist is a Denylist held in process memory. Entries are dropped
+// once no token they could reject is still unexpired. It is not shared
+// between server instances.
+type MemoryDenylist struct {
+	mu          sync.Mutex
+	maxTokenTTL time.Duration
+	tokens      map[string]time.Time
+	cutoffs     map[uuid.UUID]time.Time
+}
+
+// NewMemoryDenylist returns an empty denylist for tokens that live at most
+// maxTokenTTL.
+func NewMemoryDenylist(maxTokenTTL time.Duration) *MemoryDenylist {
+	return &MemoryDenylist{
+		maxTokenTTL: maxTokenTTL,
+		tokens:      map[string]time.Time{},
+		cutoffs:     map[uuid.UUID]time.Time{},
+	}
+}
+
+func (d *MemoryDenylist) RevokeToken(_ context.Context, jti string, expiresAt time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sweep(time.Now())
+	if expiresAt.After(d.tokens[jti]) {
+		d.tokens[jti] = expiresAt
+	}
+	return nil
+}
+
+func (d *MemoryDenylist) RevokeUserTokens(_ context.Context, userID uuid.UUID, notBefore time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sweep(time.Now())
+	notBefore = notBefore.Truncate(time.Second)
+	if notBefore.After(d.cutoffs[userID]) {
+		d.cutoffs[userID] = notBefore
+	}
+	return nil
+}
+
+func (d *MemoryDenylist) IsRevoked(_ context.Context, jti string, userID uuid.UUID, issuedAt time.Time) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if expiresAt, ok := d.tokens[jti]; ok && now.Before(expiresAt) {
+		return true, nil
+	}
+	notBefore, ok := d.cutoffs[userID]
+	return ok && now.Before(notBefore.Add(d.maxTokenTTL)) && issuedAt.Before(notBefore), nil
+}
+
+// sweep drops entries that can no longer reject an unexpired token.
+func (d *MemoryDenylist) sweep(now time.Time) {
+	for jti, expiresAt := range d.tokens {
+		if !now.Before(expiresAt) {
+			delete(d.tokens, jti)
+		}
+	}
+	for userID, notBefore := range d.cutoffs {
+		if !now.Before(notBefore.Add(d.maxTokenTTL)) {
+			delete(d.cutoffs, userID)
+		}
+	}
+}
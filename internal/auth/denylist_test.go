@@ -0,0 +1,106 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+)
+
+func TestMemoryDenylistRevokeToken(t *testing.T) {
+	denylist := auth.NewMemoryDenylist(time.Hour)
+	userID := uuid.New()
+	now := time.Now()
+
+	require.NoError(t, denylist.RevokeToken(t.Context(), "revoked", now.Add(time.Hour)))
+	require.NoError(t, denylist.RevokeToken(t.Context(), "expired", now.Add(-time.Second)))
+
+	revoked, err := denylist.IsRevoked(t.Context(), "revoked", userID, now)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = denylist.IsRevoked(t.Context(), "expired", userID, now)
+	require.NoError(t, err)
+	assert.False(t, revoked, "entries for expired tokens should be dropped")
+
+	revoked, err = denylist.IsRevoked(t.Context(), "other", userID, now)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestMemoryDenylistRevokeUserTokens(t *testing.T) {
+	denylist := auth.NewMemoryDenylist(time.Hour)
+	userID := uuid.New()
+	cutoff := time.Now().Truncate(time.Second).Add(500 * time.Millisecond)
+
+	require.NoError(t, denylist.RevokeUserTokens(t.Context(), userID, cutoff))
+
+	tests := []struct {
+		name     string
+		userID   uuid.UUID
+		issuedAt time.Time
+		want     bool
+	}{
+		{"issued before the cutoff", userID, cutoff.Add(-time.Minute), true},
+		{"issued in the cutoff's second", userID, cutoff.Truncate(time.Second), false},
+		{"issued after the cutoff", userID, cutoff.Add(time.Second), false},
+		{"another user", uuid.New(), cutoff.Add(-time.Minute), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := denylist.IsRevoked(t.Context(), uuid.NewString(), tt.userID, tt.issuedAt)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, revoked)
+		})
+	}
+
+	// Once every token it could reject has expired, the cutoff is dropped.
+	expired := auth.NewMemoryDenylist(time.Minute)
+	require.NoError(t, expired.RevokeUserTokens(t.Context(), userID, time.Now().Add(-time.Hour)))
+	revoked, err := expired.IsRevoked(t.Context(), "", userID, time.Now().Add(-2*time.Hour))
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestKeyringDenylist(t *testing.T) {
+	keys := auth.NewHMACKeyring("secret")
+	denylist := auth.NewMemoryDenylist(time.Hour)
+	keys.UseDenylist(denylist)
+
+	userID := uuid.New()
+	first, err := keys.MakeJWT(userID, time.Minute)
+	require.NoError(t, err)
+	second, err := keys.MakeJWT(userID, time.Minute)
+	require.NoError(t, err)
+
+	claims, err := keys.ParseAccessToken(t.Context(), first)
+	require.NoError(t, err)
+	require.NotEmpty(t, claims.ID, "access tokens should carry a jti")
+	require.NoError(t, denylist.RevokeToken(t.Context(), claims.ID, claims.ExpiresAt.Time))
+
+	_, err = keys.ValidateJWT(t.Context(), first)
+	require.ErrorIs(t, err, auth.ErrTokenRevoked)
+	_, err = keys.ValidateJWT(t.Context(), second)
+	require.NoError(t, err, "revoking one token should leave the others valid")
+
+	// A cutoff rejects tokens issued before it, here one backdated a minute.
+	old, err := keys.Sign(&jwt.RegisteredClaims{
+		Subject:   userID.String(),
+		IssuedAt:  jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	require.NoError(t, err)
+	require.NoError(t, denylist.RevokeUserTokens(t.Context(), userID, time.Now()))
+	_, err = keys.ValidateJWT(t.Context(), old)
+	require.ErrorIs(t, err, auth.ErrTokenRevoked)
+
+	fresh, err := keys.MakeJWT(userID, time.Minute)
+	require.NoError(t, err)
+	_, err = keys.ValidateJWT(t.Context(), fresh)
+	require.NoError(t, err, "tokens issued after a cutoff should be valid")
+}
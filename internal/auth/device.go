@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+)
+
+const (
+	// userCodeAlphabet has no vowels, so codes never spell words, and no
+	// letters easily confused with each other or with digits.
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+)
+
+// MakeUserCode makes a random user code for the device authorization grant,
+// such as "WDJB-MJHT", short enough to type from another screen. Its eight
+// characters give about 34 bits, enough for a code that lives minutes and
+// can only be entered after signing in.
+func MakeUserCode() (string, error) {
+	code := make([]byte, userCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(userCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = userCodeAlphabet[n.Int64()]
+	}
+	return formatUserCode(string(code)), nil
+}
+
+// NormalizeUserCode puts a user code as typed into the form MakeUserCode
+// returns, ignoring case, spaces and dashes.
+func NormalizeUserCode(code string) string {
+	code = strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+	if len(code) != userCodeLength {
+		return code
+	}
+	return formatUserCode(code)
+}
+
+func formatUserCode(code string) string {
+	return code[:userCodeLength/2] + "-" + code[userCodeLength/2:]
+}
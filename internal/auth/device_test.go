@@ -0,0 +1,30 @@
+package auth_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+)
+
+func TestMakeUserCode(t *testing.T) {
+	format := regexp.MustCompile(`^[BCDFGHJKLMNPQRSTVWXZ]{4}-[BCDFGHJKLMNPQRSTVWXZ]{4}$`)
+	seen := map[string]bool{}
+	for range 100 {
+		code, err := auth.MakeUserCode()
+		require.NoError(t, err)
+		assert.Regexp(t, format, code)
+		seen[code] = true
+	}
+	assert.Greater(t, len(seen), 90, "codes should be random")
+}
+
+func TestNormalizeUserCode(t *testing.T) {
+	for _, typed := range []string{"WDJB-MJHT", "wdjbmjht", " wdjb mjht ", "WDJ-BMJ-HT"} {
+		assert.Equal(t, "WDJB-MJHT", auth.NormalizeUserCode(typed), typed)
+	}
+	assert.Equal(t, "WDJB", auth.NormalizeUserCode("wdjb"))
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	keyFileExt     = ".pem"
+	currentKeyFile = "current"
+	keyFileMode    = 0o600
+	keyDirMode     = 0o700
+)
+
+var ErrCurrentKey = errors.New("the current signing key cannot be removed")
+
+// KeyDir is a directory of signing keys, one <kid>.pem file per key, with
+// the kid of the key used for signing in a file named "current". Every key
+// in the directory is accepted for verification, so rotating is: add a new
+// key, make it current, and remove the old one once its tokens have expired.
+type KeyDir string
+
+// Keys returns every key in the directory and the kid of the current one,
+// sorted by kid.
+func (d KeyDir) Keys() ([]*Key, string, error) {
+	paths, err := filepath.Glob(filepath.Join(string(d), "*"+keyFileExt))
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Strings(paths)
+
+	keys := make([]*Key, 0, len(paths))
+	for _, path := range paths {
+		data, readErr := os.ReadFile(path) //nolint:gosec // path comes from globbing the configured key directory
+		if readErr != nil {
+			return nil, "", readErr
+		}
+		key, parseErr := ParseKeyPEM(data)
+		if parseErr != nil {
+			return nil, "", fmt.Errorf("%s: %w", path, parseErr)
+		}
+		keys = append(keys, key)
+	}
+
+	current, err := os.ReadFile(filepath.Join(string(d), currentKeyFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, "", err
+	}
+	return keys, strings.TrimSpace(string(current)), nil
+}
+
+// Load builds a keyring that signs with the current key and verifies with
+// all of them.
+func (d KeyDir) Load() (*Keyring, error) {
+	keys, current, err := d.Keys()
+	if err != nil {
+		return nil, err
+	}
+	if current == "" {
+		return nil, fmt.Errorf("no current signing key in %s", d)
+	}
+
+	var (
+		signing    *Key
+		verifyOnly []*Key
+	)
+	for _, key := range keys {
+		if key.ID == current {
+			signing = key
+		} else {
+			verifyOnly = append(verifyOnly, key)
+		}
+	}
+	if signing == nil {
+		return nil, fmt.Errorf("%w: current key %q is not in %s", ErrUnknownKey, current, d)
+	}
+	return NewKeyring(signing, verifyOnly...), nil
+}
+
+// Add writes key to the directory without making it current.
+func (d KeyDir) Add(key *Key) error {
+	data, err := key.MarshalPEM()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(string(d), keyDirMode); err != nil {
+		return err
+	}
+	return os.WriteFile(d.keyPath(key.ID), data, keyFileMode)
+}
+
+// SetCurrent makes the key with the given kid the signing key.
+func (d KeyDir) SetCurrent(kid string) error {
+	if _, err := os.Stat(d.keyPath(kid)); err != nil {
+		return fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+
+	// Write then rename so a running server never reads a partial file.
+	tmp := filepath.Join(string(d), currentKeyFile+".tmp")
+	if err := os.WriteFile(tmp, []byte(kid+"\n"), keyFileMode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(string(d), currentKeyFile))
+}
+
+// Remove deletes a key that is no longer used for signing. Tokens signed
+// with it stop verifying.
+func (d KeyDir) Remove(kid string) error {
+	_, current, err := d.Keys()
+	if err != nil {
+		return err
+	}
+	if kid == current {
+		return ErrCurrentKey
+	}
+	if err = os.Remove(d.keyPath(kid)); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	return err
+}
+
+// keyPath returns the file for kid. Thumbprints are base64url, so a kid
+// never contains a path separator; anything else is reduced to its base
+// name.
+func (d KeyDir) keyPath(kid string) string {
+	return filepath.Join(string(d), filepath.Base(kid)+keyFileExt)
+}
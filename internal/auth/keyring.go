@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const jwtLeeway = 5 * time.Second
+
+var (
+	ErrUnknownKey          = errors.New("unknown signing key")
+	errLegacySecretRetired = errors.New("tokens signed with the legacy secret are no longer accepted")
+)
+
+// Keyring signs access tokens with one key and verifies tokens signed by any
+// key it holds, so a new signing key can be rolled out while tokens issued
+// with the previous one are still valid.
+type Keyring struct {
+	signing *Key
+	keys    map[string]*Key
+	// secret is the shared HS256 secret. It signs tokens when there is no
+	// signing key, and verifies tokens without a kid when there is one.
+	secret []byte
+	// legacyLoadedAt and legacyUntil bound the tokens secret verifies when
+	// there is a signing key: issued before it was accepted, and presented
+	// while tokens issued then may still be unexpired.
+	legacyLoadedAt time.Time
+	legacyUntil    time.Time
+	denylist       Denylist
+	personalTokens PersonalTokenStore
+}
+
+// JWKS is a JSON Web Key Set document.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewKeyring returns a keyring that signs with signing and also accepts
+// tokens signed by any of verifyOnly.
+func NewKeyring(signing *Key, verifyOnly ...*Key) *Keyring {
+	k := &Keyring{signing: signing, keys: make(map[string]*Key, len(verifyOnly)+1)}
+	k.keys[signing.ID] = signing
+	for _, key := range verifyOnly {
+		k.keys[key.ID] = key
+	}
+	return k
+}
+
+// NewHMACKeyring returns a keyring that signs and verifies with a shared
+// HS256 secret, like MakeJWT and ValidateJWT.
+func NewHMACKeyring(secret string) *Keyring {
+	return &Keyring{keys: map[string]*Key{}, secret: []byte(secret)}
+}
+
+// AcceptLegacySecret makes the keyring also accept HS256 tokens signed with
+// secret, so tokens issued before switching to asymmetric keys keep working
+// until they expire. Only tokens issued before the call are accepted, and
+// none once ttl, the longest lifetime such a token can have, has passed.
+// An empty secret is ignored.
+func (k *Keyring) AcceptLegacySecret(secret string, ttl time.Duration) {
+	if secret != "" {
+		k.secret = []byte(secret)
+		k.legacyLoadedAt = time.Now()
+		k.legacyUntil = k.legacyLoadedAt.Add(ttl)
+	}
+}
+
+// UseDenylist makes ValidateJWT reject access tokens revoked in d.
+func (k *Keyring) UseDenylist(d Denylist) {
+	k.denylist = d
+}
+
+// Sign signs claims with the current key, setting the kid header.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	if k.signing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.secret)
+	}
+	token := jwt.NewWithClaims(k.signing.method, claims)
+	token.Header["kid"] = k.signing.ID
+	return token.SignedString(k.signing.private)
+}
+
+// Parse verifies tokenString and decodes it into claims. The kid header
+// selects the verification key, and the token must use that key's
+// algorithm, so a public key can never be used as an HMAC secret.
+func (k *Keyring) Parse(tokenString string, claims jwt.Claims) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, k.keyFunc, jwt.WithLeeway(jwtLeeway))
+	if err != nil {
+		return fmt.Errorf("failed to parse or validate JWT: %w", err)
+	}
+	if !token.Valid {
+		return errors.New("invalid JWT token")
+	}
+	return nil
+}
+
+// MakeJWT issues an access token for userID.
+func (k *Keyring) MakeJWT(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	return k.Sign(accessClaims(userID, expiresIn))
+}
+
+// ValidateJWT verifies a first-party access token and returns its user ID.
+// Tokens issued to OAuth clients are rejected; see Authorize.
+func (k *Keyring) ValidateJWT(ctx context.Context, tokenString string) (uuid.UUID, error) {
+	return k.Authorize(ctx, tokenString, "")
+}
+
+// ParseAccessToken verifies an access token, checks it against the
+// denylist, and returns its claims. Access tokens carry no audience, so
+// tokens issued for another purpose, such as a 2FA challenge, are rejected.
+func (k *Keyring) ParseAccessToken(ctx context.Context, tokenString string) (*AccessClaims, error) {
+	return k.parseFor(ctx, tokenString, "")
+}
+
+// parseFor verifies a token issued for audience, where an empty audience
+// means an access token, and checks it against the denylist.
+func (k *Keyring) parseFor(ctx context.Context, tokenString, audience string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	if err := k.Parse(tokenString, claims); err != nil {
+		return nil, err
+	}
+	if audience == "" && len(claims.Audience) > 0 || audience != "" && !slices.Contains(claims.Audience, audience) {
+		return nil, fmt.Errorf("%w: unexpected audience %v", jwt.ErrTokenInvalidAudience, claims.Audience)
+	}
+	if k.denylist == nil {
+		return claims, nil
+	}
+
+	userID, err := subjectUserID(&claims.RegisteredClaims)
+	if err != nil {
+		return nil, err
+	}
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	revoked, err := k.denylist.IsRevoked(ctx, claims.ID, userID, issuedAt)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// JWKS returns the public keys that tokens may be signed with. The shared
+// HMAC secret is never published.
+func (k *Keyring) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(k.keys))}
+	for _, key := range k.keys {
+		set.Keys = append(set.Keys, key.JWK())
+	}
+	// Current key first, the rest in a stable order.
+	slices.SortFunc(set.Keys, func(a, b JWK) int {
+		switch {
+		case a.KeyID == b.KeyID:
+			return 0
+		case k.signing != nil && a.KeyID == k.signing.ID:
+			return -1
+		case k.signing != nil && b.KeyID == k.signing.ID:
+			return 1
+		}
+		return strings.Compare(a.KeyID, b.KeyID)
+	})
+	return set
+}
+
+func (k *Keyring) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, hasKid := token.Header["kid"].(string)
+	if !hasKid {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || len(k.secret) == 0 {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		if k.signing != nil {
+			if err := k.checkLegacy(token); err != nil {
+				return nil, err
+			}
+		}
+		return k.secret, nil
+	}
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	if token.Method.Alg() != key.Algorithm() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.public(), nil
+}
+
+// checkLegacy rejects a token signed with the legacy secret unless it was
+// issued before the secret was accepted and that was recent enough for the
+// token to still be unexpired.
+func (k *Keyring) checkLegacy(token *jwt.Token) error {
+	if !time.Now().Before(k.legacyUntil) {
+		return errLegacySecretRetired
+	}
+	issuedAt, err := token.Claims.GetIssuedAt()
+	if err != nil {
+		return err
+	}
+	if issuedAt == nil || !issuedAt.Before(k.legacyLoadedAt) {
+		return errLegacySecretRetired
+	}
+	return nil
+}
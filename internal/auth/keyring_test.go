@@ -0,0 +1,211 @@
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+)
+
+func TestKeyringSignAndValidate(t *testing.T) {
+	for _, alg := range []string{auth.AlgEdDSA, auth.AlgRS256} {
+		t.Run(alg, func(t *testing.T) {
+			key, err := auth.GenerateKey(alg)
+			require.NoError(t, err)
+			keys := auth.NewKeyring(key)
+
+			userID := uuid.New()
+			token, err := keys.MakeJWT(userID, time.Minute)
+			require.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, alg, parsed.Header["alg"])
+			assert.Equal(t, key.ID, parsed.Header["kid"])
+
+			got, err := keys.ValidateJWT(t.Context(), token)
+			require.NoError(t, err)
+			assert.Equal(t, userID, got)
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	oldKey, err := auth.GenerateKey(auth.AlgEdDSA)
+	require.NoError(t, err)
+	newKey, err := auth.GenerateKey(auth.AlgEdDSA)
+	require.NoError(t, err)
+
+	userID := uuid.New()
+	oldToken, err := auth.NewKeyring(oldKey).MakeJWT(userID, time.Minute)
+	require.NoError(t, err)
+
+	rotated := auth.NewKeyring(newKey, oldKey)
+	got, err := rotated.ValidateJWT(t.Context(), oldToken)
+	require.NoError(t, err, "tokens signed with a previous key should still verify")
+	assert.Equal(t, userID, got)
+
+	_, err = auth.NewKeyring(newKey).ValidateJWT(t.Context(), oldToken)
+	require.ErrorIs(t, err, auth.ErrUnknownKey, "tokens signed with a removed key should not verify")
+}
+
+func TestKeyringRejectsAlgorithmConfusion(t *testing.T) {
+	key, err := auth.GenerateKey(auth.AlgEdDSA)
+	require.NoError(t, err)
+	keys := auth.NewKeyring(key)
+	keys.AcceptLegacySecret("legacy-secret", time.Hour)
+
+	// An HS256 token claiming the Ed25519 kid, "signed" with the public key.
+	public, err := base64.RawURLEncoding.DecodeString(key.JWK().X)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.RegisteredClaims{Subject: uuid.NewString()})
+	forged.Header["kid"] = key.ID
+	forgedString, err := forged.SignedString(public)
+	require.NoError(t, err)
+
+	_, err = keys.ValidateJWT(t.Context(), forgedString)
+	assert.Error(t, err)
+}
+
+func TestKeyringLegacySecret(t *testing.T) {
+	key, err := auth.GenerateKey(auth.AlgEdDSA)
+	require.NoError(t, err)
+	userID := uuid.New()
+	legacyToken, err := auth.MakeJWT(userID, "legacy-secret", time.Minute)
+	require.NoError(t, err)
+
+	keys := auth.NewKeyring(key)
+	_, err = keys.ValidateJWT(t.Context(), legacyToken)
+	require.Error(t, err, "HS256 tokens should be rejected without a legacy secret")
+
+	keys.AcceptLegacySecret("legacy-secret", time.Hour)
+	got, err := keys.ValidateJWT(t.Context(), legacyToken)
+	require.NoError(t, err)
+	assert.Equal(t, userID, got)
+}
+
+func TestKeyringLegacySecretWindow(t *testing.T) {
+	key, err := auth.GenerateKey(auth.AlgEdDSA)
+	require.NoError(t, err)
+	legacyToken, err := auth.MakeJWT(uuid.New(), "legacy-secret", time.Minute)
+	require.NoError(t, err)
+
+	retired := auth.NewKeyring(key)
+	retired.AcceptLegacySecret("legacy-secret", 0)
+	_, err = retired.ValidateJWT(t.Context(), legacyToken)
+	require.Error(t, err, "HS256 tokens should be rejected once the window has passed")
+
+	keys := auth.NewKeyring(key)
+	keys.AcceptLegacySecret("legacy-secret", time.Hour)
+	now := time.Now()
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.RegisteredClaims{
+		Subject:   uuid.NewString(),
+		IssuedAt:  jwt.NewNumericDate(now.Add(time.Minute)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+	}).SignedString([]byte("legacy-secret"))
+	require.NoError(t, err)
+	_, err = keys.ValidateJWT(t.Context(), forged)
+	assert.Error(t, err, "HS256 tokens issued after the switch should be rejected")
+}
+
+func TestKeyringJWKS(t *testing.T) {
+	current, err := auth.GenerateKey(auth.AlgEdDSA)
+	require.NoError(t, err)
+	previous, err := auth.GenerateKey(auth.AlgRS256)
+	require.NoError(t, err)
+
+	set := auth.NewKeyring(current, previous).JWKS()
+	require.Len(t, set.Keys, 2)
+	assert.Equal(t, current.ID, set.Keys[0].KeyID, "the current key should be listed first")
+	assert.Equal(t, "OKP", set.Keys[0].KeyType)
+	assert.Equal(t, "Ed25519", set.Keys[0].Curve)
+	assert.Equal(t, "RSA", set.Keys[1].KeyType)
+	assert.Equal(t, "AQAB", set.Keys[1].E)
+
+	assert.Empty(t, auth.NewHMACKeyring("secret").JWKS().Keys, "the shared secret must never be published")
+}
+
+func TestKeyThumbprint(t *testing.T) {
+	// RFC 8037 appendix A.3.
+	public, err := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	require.NoError(t, err)
+	seed, err := base64.RawURLEncoding.DecodeString("nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A")
+	require.NoError(t, err)
+	private := ed25519.NewKeyFromSeed(seed)
+	require.Equal(t, ed25519.PublicKey(public), private.Public())
+
+	key, err := auth.NewKey(private)
+	require.NoError(t, err)
+	assert.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", key.ID)
+}
+
+func TestKeyDir(t *testing.T) {
+	dir := auth.KeyDir(filepath.Join(t.TempDir(), "keys"))
+
+	_, err := dir.Load()
+	require.Error(t, err, "a directory without a current key cannot sign")
+
+	first, err := auth.GenerateKey(auth.AlgEdDSA)
+	require.NoError(t, err)
+	require.NoError(t, dir.Add(first))
+	require.NoError(t, dir.SetCurrent(first.ID))
+
+	second, err := auth.GenerateKey(auth.AlgEdDSA)
+	require.NoError(t, err)
+	require.NoError(t, dir.Add(second))
+
+	info, err := os.Stat(filepath.Join(string(dir), first.ID+".pem"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	keys, err := dir.Load()
+	require.NoError(t, err)
+	userID := uuid.New()
+	token, err := keys.MakeJWT(userID, time.Minute)
+	require.NoError(t, err)
+
+	require.NoError(t, dir.SetCurrent(second.ID))
+	require.ErrorIs(t, dir.Remove(second.ID), auth.ErrCurrentKey)
+	require.ErrorIs(t, dir.SetCurrent("missing"), auth.ErrUnknownKey)
+
+	rotated, err := dir.Load()
+	require.NoError(t, err)
+	got, err := rotated.ValidateJWT(t.Context(), token)
+	require.NoError(t, err)
+	assert.Equal(t, userID, got)
+
+	require.NoError(t, dir.Remove(first.ID))
+	listed, current, err := dir.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, current)
+	require.Len(t, listed, 1)
+	assert.Equal(t, second.ID, listed[0].ID)
+}
+
+func TestTwoFactorChallengeIsNotAnAccessToken(t *testing.T) {
+	keys := auth.NewHMACKeyring("secret")
+	userID := uuid.New()
+
+	challenge, err := keys.MakeTwoFactorChallenge(userID, time.Minute)
+	require.NoError(t, err)
+	_, err = keys.ValidateJWT(t.Context(), challenge)
+	require.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+
+	claims, err := keys.ParseTwoFactorChallenge(t.Context(), challenge)
+	require.NoError(t, err)
+	assert.Equal(t, userID.String(), claims.Subject)
+
+	accessToken, err := keys.MakeJWT(userID, time.Minute)
+	require.NoError(t, err)
+	_, err = keys.ParseTwoFactorChallenge(t.Context(), accessToken)
+	require.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms supported for asymmetric keys, as they appear in the
+// JWT "alg" header.
+const (
+	AlgEdDSA = "EdDSA"
+	AlgRS256 = "RS256"
+)
+
+const (
+	rsaKeyBits    = 3072
+	minRSAKeyBits = 2048
+	pemBlockType  = "PRIVATE KEY"
+)
+
+var ErrUnsupportedKey = errors.New("unsupported signing key")
+
+// Key is an asymmetric JWT signing key. Its ID is the RFC 7638 thumbprint of
+// the public key, so the same key always gets the same kid.
+type Key struct {
+	ID      string
+	method  jwt.SigningMethod
+	private crypto.Signer
+}
+
+// JWK is the public half of a Key in JSON Web Key form.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// GenerateKey creates a new key for alg, which is AlgEdDSA or AlgRS256.
+func GenerateKey(alg string) (*Key, error) {
+	var (
+		private crypto.Signer
+		err     error
+	)
+	switch alg {
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	default:
+		return nil, fmt.Errorf("%w: algorithm %q", ErrUnsupportedKey, alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewKey(private)
+}
+
+// NewKey wraps an Ed25519 or RSA private key. RSA keys shorter than 2048
+// bits are rejected.
+func NewKey(private crypto.Signer) (*Key, error) {
+	key := &Key{private: private}
+	switch p := private.(type) {
+	case ed25519.PrivateKey:
+		key.method = jwt.SigningMethodEdDSA
+	case *rsa.PrivateKey:
+		if p.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("%w: RSA keys must be at least %d bits", ErrUnsupportedKey, minRSAKeyBits)
+		}
+		key.method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, private)
+	}
+
+	thumbprint, err := key.thumbprint()
+	if err != nil {
+		return nil, err
+	}
+	key.ID = thumbprint
+	return key, nil
+}
+
+// ParseKeyPEM reads a key written by MarshalPEM.
+func ParseKeyPEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != pemBlockType {
+		return nil, fmt.Errorf("%w: expected a PEM %q block", ErrUnsupportedKey, pemBlockType)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, parsed)
+	}
+	return NewKey(private)
+}
+
+// Algorithm returns the JWT "alg" the key signs with.
+func (k *Key) Algorithm() string {
+	return k.method.Alg()
+}
+
+// MarshalPEM encodes the private key as PKCS #8 PEM.
+func (k *Key) MarshalPEM() ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.private)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemBlockType, Bytes: der}), nil
+}
+
+// JWK returns the public key for publishing in a JWKS document.
+func (k *Key) JWK() JWK {
+	jwk := JWK{KeyID: k.ID, Use: "sig", Algorithm: k.Algorithm()}
+	switch public := k.private.Public().(type) {
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	}
+	return jwk
+}
+
+// thumbprint computes the RFC 7638 thumbprint: the SHA-256 of the required
+// public members, serialised with sorted keys and no whitespace.
+func (k *Key) thumbprint() (string, error) {
+	jwk := k.JWK()
+	members := map[string]string{"kty": jwk.KeyType}
+	switch jwk.KeyType {
+	case "OKP":
+		members["crv"] = jwk.Curve
+		members["x"] = jwk.X
+	case "RSA":
+		members["e"] = jwk.E
+		members["n"] = jwk.N
+	}
+	// encoding/json sorts map keys, which is the ordering RFC 7638 requires.
+	canonical, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+func (k *Key) public() crypto.PublicKey {
+	return k.private.Public()
+}
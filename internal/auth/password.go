@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// Argon2idMemory, Argon2idIterations and Argon2idParallelism are the
+	// OWASP recommended minimums: 19 MiB, two passes and one lane.
+	Argon2idMemory      = 19 * 1024
+	Argon2idIterations  = 2
+	Argon2idParallelism = 1
+
+	argon2idSaltLength = 16
+	argon2idKeyLength  = 32
+	argon2idPrefix     = "$argon2id$"
+)
+
+var (
+	ErrPasswordMismatch    = errors.New("password does not match")
+	ErrInvalidPasswordHash = errors.New("invalid password hash")
+)
+
+// PasswordHasher hashes passwords for storage. Check accepts hashes in any
+// supported format, so passwords stored under an older configuration keep
+// working, and NeedsRehash reports which of them should be replaced.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Check(hash, password string) error
+	NeedsRehash(hash string) bool
+}
+
+// Argon2idHasher hashes passwords with argon2id and encodes them in the PHC
+// string format, e.g. "$argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>".
+type Argon2idHasher struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// NewArgon2idHasher returns an Argon2idHasher using memory KiB, iterations
+// passes and parallelism lanes.
+func NewArgon2idHasher(memory, iterations, parallelism int) (*Argon2idHasher, error) {
+	if parallelism < 1 || parallelism > 255 {
+		return nil, fmt.Errorf("argon2id parallelism must be between 1 and 255, got %d", parallelism)
+	}
+	if iterations < 1 || iterations > 1<<16 {
+		return nil, fmt.Errorf("argon2id iterations must be between 1 and 65536, got %d", iterations)
+	}
+	if memory < 8*parallelism || memory > 1<<22 {
+		return nil, fmt.Errorf("argon2id memory must be between %d and 4194304 KiB, got %d", 8*parallelism, memory)
+	}
+	return &Argon2idHasher{
+		memory:      uint32(memory),     //nolint:gosec // bounded above
+		iterations:  uint32(iterations), //nolint:gosec // bounded above
+		parallelism: uint8(parallelism), //nolint:gosec // bounded above
+	}, nil
+}
+
+// Hash returns the PHC encoded argon2id hash of password under a random salt.
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2idSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.iterations, h.memory, h.parallelism, argon2idKeyLength)
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, h.memory, h.iterations, h.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Check compares password with hash.
+func (h *Argon2idHasher) Check(hash, password string) error {
+	return CheckPassword(hash, password)
+}
+
+// NeedsRehash reports whether hash is not an argon2id hash with h's
+// parameters.
+func (h *Argon2idHasher) NeedsRehash(hash string) bool {
+	params, _, key, err := parseArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	return params != argon2idParams{h.memory, h.iterations, h.parallelism} || len(key) != argon2idKeyLength
+}
+
+// BcryptHasher hashes passwords with bcrypt.
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher returns a BcryptHasher using cost.
+func NewBcryptHasher(cost int) (*BcryptHasher, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cost)
+	}
+	return &BcryptHasher{cost: cost}, nil
+}
+
+// Hash returns the bcrypt hash of password.
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	return string(hashed), err
+}
+
+// Check compares password with hash.
+func (h *BcryptHasher) Check(hash, password string) error {
+	return CheckPassword(hash, password)
+}
+
+// NeedsRehash reports whether hash is not a bcrypt hash at h's cost.
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.cost
+}
+
+// HashPassword hashes password with bcrypt at its default cost. Handlers
+// hash with the server's configured PasswordHasher instead.
+func HashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hashed), err
+}
+
+// CheckPassword compares password with an argon2id or bcrypt hash. A wrong
+// password gives an error wrapping ErrPasswordMismatch.
+func CheckPassword(hash, password string) error {
+	if !strings.HasPrefix(hash, argon2idPrefix) {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return fmt.Errorf("%w: %w", ErrPasswordMismatch, err)
+		}
+		return err
+	}
+
+	params, salt, want, err := parseArgon2id(hash)
+	if err != nil {
+		return err
+	}
+	//nolint:gosec // the key length is at most the length of a stored hash
+	got := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(want)))
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// parseArgon2id splits a PHC encoded argon2id hash into its parameters,
+// salt and key.
+func parseArgon2id(hash string) (argon2idParams, []byte, []byte, error) {
+	var params argon2idParams
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism)
+	if err != nil || params.iterations == 0 || params.parallelism == 0 {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,97 @@
+package auth_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+)
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher, err := auth.NewArgon2idHasher(auth.Argon2idMemory, auth.Argon2idIterations, auth.Argon2idParallelism)
+	require.NoError(t, err)
+
+	hash, err := hasher.Hash("correct horse battery staple")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$"), hash)
+
+	other, err := hasher.Hash("correct horse battery staple")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "each hash should use a fresh salt")
+
+	require.NoError(t, hasher.Check(hash, "correct horse battery staple"))
+	require.ErrorIs(t, hasher.Check(hash, "wrong"), auth.ErrPasswordMismatch)
+	assert.False(t, hasher.NeedsRehash(hash))
+}
+
+func TestArgon2idKnownHash(t *testing.T) {
+	// A test vector from golang.org/x/crypto/argon2: password "password",
+	// salt "somesalt", two passes over 64 KiB in one lane.
+	const hash = "$argon2id$v=19$m=64,t=2,p=1$c29tZXNhbHQ$Bo1ismRVk2qm6+YAYLCmWHDb+j3fjUH3"
+
+	require.NoError(t, auth.CheckPassword(hash, "password"))
+	require.ErrorIs(t, auth.CheckPassword(hash, "Password"), auth.ErrPasswordMismatch)
+}
+
+func TestCheckPasswordRejectsMalformedHashes(t *testing.T) {
+	for _, hash := range []string{
+		"$argon2id$v=19$m=19456,t=2,p=1$c29tZXNhbHQ",
+		"$argon2id$v=16$m=19456,t=2,p=1$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=19$m=19456,t=0,p=1$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=19$m=19456,t=2,p=1$c29tZXNhbHQ$not base64!",
+	} {
+		require.ErrorIs(t, auth.CheckPassword(hash, "password"), auth.ErrInvalidPasswordHash, hash)
+	}
+}
+
+func TestBcryptHasher(t *testing.T) {
+	hasher, err := auth.NewBcryptHasher(bcrypt.MinCost)
+	require.NoError(t, err)
+
+	hash, err := hasher.Hash("hunter2")
+	require.NoError(t, err)
+	require.NoError(t, hasher.Check(hash, "hunter2"))
+	require.ErrorIs(t, hasher.Check(hash, "hunter3"), auth.ErrPasswordMismatch)
+	assert.False(t, hasher.NeedsRehash(hash))
+
+	_, err = auth.NewBcryptHasher(bcrypt.MaxCost + 1)
+	require.Error(t, err)
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argon, err := auth.NewArgon2idHasher(auth.Argon2idMemory, auth.Argon2idIterations, auth.Argon2idParallelism)
+	require.NoError(t, err)
+	stronger, err := auth.NewArgon2idHasher(2*auth.Argon2idMemory, auth.Argon2idIterations, auth.Argon2idParallelism)
+	require.NoError(t, err)
+	cheapBcrypt, err := auth.NewBcryptHasher(bcrypt.MinCost)
+	require.NoError(t, err)
+	costlyBcrypt, err := auth.NewBcryptHasher(bcrypt.MinCost + 1)
+	require.NoError(t, err)
+
+	argonHash, err := argon.Hash("password")
+	require.NoError(t, err)
+	bcryptHash, err := cheapBcrypt.Hash("password")
+	require.NoError(t, err)
+
+	assert.True(t, argon.NeedsRehash(bcryptHash), "bcrypt hashes should be upgraded to argon2id")
+	assert.True(t, stronger.NeedsRehash(argonHash), "hashes with weaker parameters should be upgraded")
+	assert.True(t, costlyBcrypt.NeedsRehash(bcryptHash))
+	assert.True(t, cheapBcrypt.NeedsRehash(argonHash))
+
+	// Either hasher still checks the other's hashes until they are replaced.
+	require.NoError(t, argon.Check(bcryptHash, "password"))
+	require.NoError(t, cheapBcrypt.Check(argonHash, "password"))
+}
+
+func TestNewArgon2idHasherValidates(t *testing.T) {
+	_, err := auth.NewArgon2idHasher(auth.Argon2idMemory, 0, 1)
+	require.Error(t, err)
+	_, err = auth.NewArgon2idHasher(auth.Argon2idMemory, 1, 0)
+	require.Error(t, err)
+	_, err = auth.NewArgon2idHasher(7, 1, 1)
+	require.Error(t, err)
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PersonalTokenPrefix starts every personal access token, telling them
+// apart from JWTs and letting secret scanners spot leaked ones.
+const PersonalTokenPrefix = "chirpy_pat_"
+
+var ErrInvalidPersonalToken = errors.New("invalid or expired personal access token")
+
+// PersonalToken is what a personal access token grants.
+type PersonalToken struct {
+	UserID uuid.UUID
+	Scopes []string
+	// ExpiresAt is zero for tokens that last until they are deleted.
+	ExpiresAt time.Time
+}
+
+// PersonalTokenStore looks up personal access tokens.
+type PersonalTokenStore interface {
+	// LookupPersonalToken returns the token whose HashToken is tokenHash,
+	// or ErrInvalidPersonalToken if there is none.
+	LookupPersonalToken(ctx context.Context, tokenHash string) (PersonalToken, error)
+}
+
+// MakePersonalToken makes a random personal access token.
+func MakePersonalToken() (string, error) {
+	token, err := MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	return PersonalTokenPrefix + token, nil
+}
+
+// UsePersonalTokens makes Authorize accept personal access tokens found in
+// s alongside JWTs.
+func (k *Keyring) UsePersonalTokens(s PersonalTokenStore) {
+	k.personalTokens = s
+}
+
+// authorizePersonalToken is Authorize for personal access tokens. Like
+// tokens issued to OAuth clients they only grant their scopes. They are
+// revoked by deleting them from the store rather than by the denylist,
+// whose entries do not outlive access tokens.
+func (k *Keyring) authorizePersonalToken(ctx context.Context, tokenString, scope string) (uuid.UUID, error) {
+	if k.personalTokens == nil {
+		return uuid.Nil, ErrInvalidPersonalToken
+	}
+	token, err := k.personalTokens.LookupPersonalToken(ctx, HashToken(tokenString))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !token.ExpiresAt.IsZero() && !token.ExpiresAt.After(time.Now()) {
+		return uuid.Nil, ErrInvalidPersonalToken
+	}
+
+	if !slices.Contains(token.Scopes, scope) {
+		return uuid.Nil, ErrInsufficientScope
+	}
+	return token.UserID, nil
+}
+
+// isPersonalToken reports whether tokenString is a personal access token
+// rather than a JWT.
+func isPersonalToken(tokenString string) bool {
+	return strings.HasPrefix(tokenString, PersonalTokenPrefix)
+}
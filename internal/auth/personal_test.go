@@ -0,0 +1,64 @@
+package auth_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+)
+
+type personalTokenMap map[string]auth.PersonalToken
+
+func (m personalTokenMap) LookupPersonalToken(_ context.Context, tokenHash string) (auth.PersonalToken, error) {
+	token, ok := m[tokenHash]
+	if !ok {
+		return auth.PersonalToken{}, auth.ErrInvalidPersonalToken
+	}
+	return token, nil
+}
+
+func TestAuthorizePersonalToken(t *testing.T) {
+	keys := auth.NewHMACKeyring("secret")
+	userID := uuid.New()
+
+	token, err := auth.MakePersonalToken()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, auth.PersonalTokenPrefix))
+
+	_, err = keys.Authorize(t.Context(), token, auth.ScopeChirpsRead)
+	require.ErrorIs(t, err, auth.ErrInvalidPersonalToken, "personal tokens need a store")
+
+	expired, err := auth.MakePersonalToken()
+	require.NoError(t, err)
+	keys.UsePersonalTokens(personalTokenMap{
+		auth.HashToken(token): {
+			UserID: userID,
+			Scopes: []string{auth.ScopeChirpsRead},
+		},
+		auth.HashToken(expired): {
+			UserID:    userID,
+			Scopes:    []string{auth.ScopeChirpsRead},
+			ExpiresAt: time.Now().Add(-time.Minute),
+		},
+	})
+
+	got, err := keys.Authorize(t.Context(), token, auth.ScopeChirpsRead)
+	require.NoError(t, err)
+	assert.Equal(t, userID, got)
+
+	_, err = keys.Authorize(t.Context(), token, auth.ScopeChirpsWrite)
+	require.ErrorIs(t, err, auth.ErrInsufficientScope)
+	_, err = keys.ValidateJWT(t.Context(), token)
+	require.ErrorIs(t, err, auth.ErrInsufficientScope, "personal tokens should not pass as first-party")
+
+	_, err = keys.Authorize(t.Context(), expired, auth.ScopeChirpsRead)
+	require.ErrorIs(t, err, auth.ErrInvalidPersonalToken)
+	_, err = keys.Authorize(t.Context(), auth.PersonalTokenPrefix+"unknown", auth.ScopeChirpsRead)
+	require.ErrorIs(t, err, auth.ErrInvalidPersonalToken)
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+const (
+	// PKCEMethodS256 is the only code challenge method accepted; "plain"
+	// would let anyone who sees the authorization request redeem the code.
+	PKCEMethodS256 = "S256"
+
+	minCodeVerifierLength = 43
+	maxCodeVerifierLength = 128
+)
+
+// ValidCodeChallenge reports whether challenge could be an S256 code
+// challenge: the unpadded base64url encoding of a SHA-256 hash.
+func ValidCodeChallenge(challenge string) bool {
+	decoded, err := base64.RawURLEncoding.DecodeString(challenge)
+	return err == nil && len(decoded) == sha256.Size
+}
+
+// VerifyPKCE reports whether verifier matches an S256 challenge, as
+// described in RFC 7636.
+func VerifyPKCE(verifier, challenge string) bool {
+	if len(verifier) < minCodeVerifierLength || len(verifier) > maxCodeVerifierLength {
+		return false
+	}
+	for _, c := range verifier {
+		if !isUnreserved(c) {
+			return false
+		}
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(challenge)) == 1
+}
+
+// isUnreserved reports whether c is an RFC 3986 unreserved character, the
+// only ones allowed in a code verifier.
+func isUnreserved(c rune) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
@@ -0,0 +1,26 @@
+package auth_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	// The example from RFC 7636 appendix B.
+	const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	const challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	assert.True(t, auth.ValidCodeChallenge(challenge))
+	assert.True(t, auth.VerifyPKCE(verifier, challenge))
+	assert.False(t, auth.VerifyPKCE(verifier+"x", challenge))
+	assert.False(t, auth.VerifyPKCE("too-short", challenge))
+	assert.False(t, auth.VerifyPKCE(strings.Repeat("a", 129), challenge))
+	assert.False(t, auth.VerifyPKCE(strings.Repeat("a", 42)+"+", challenge), "verifiers must be unreserved characters")
+
+	assert.False(t, auth.ValidCodeChallenge(verifier[:20]))
+	assert.False(t, auth.ValidCodeChallenge(challenge+"="))
+}
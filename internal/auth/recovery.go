@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+)
+
+const (
+	recoveryCodeBytes    = 8
+	recoveryCodeLength   = 12
+	recoveryCodeGroupLen = 4
+)
+
+// MakeRecoveryCodes returns n random one-time recovery codes formatted like
+// "abcd-efgh-ijkl". Each carries 60 bits of randomness, enough that storing
+// them with HashRecoveryCode is safe.
+func MakeRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		raw := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(raw))[:recoveryCodeLength]
+
+		groups := make([]string, 0, recoveryCodeLength/recoveryCodeGroupLen)
+		for start := 0; start < len(code); start += recoveryCodeGroupLen {
+			groups = append(groups, code[start:start+recoveryCodeGroupLen])
+		}
+		codes[i] = strings.Join(groups, "-")
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode hashes a recovery code for storage or lookup. Case,
+// dashes and spaces are ignored, so codes can be typed however they were
+// written down.
+func HashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+	return HashToken(normalized)
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// The scopes an OAuth client can ask for.
+const (
+	ScopeChirpsRead  = "chirps:read"
+	ScopeChirpsWrite = "chirps:write"
+	ScopeProfile     = "profile"
+)
+
+var (
+	ErrInvalidScope      = errors.New("invalid scope")
+	ErrInsufficientScope = errors.New("access token does not grant the required scope")
+)
+
+// Scopes returns every scope in the order consent pages list them.
+func Scopes() []string {
+	return []string{ScopeChirpsRead, ScopeChirpsWrite, ScopeProfile}
+}
+
+// DescribeScope returns what scope lets a client do, phrased for the user
+// being asked to grant it.
+func DescribeScope(scope string) string {
+	switch scope {
+	case ScopeChirpsRead:
+		return "Read chirps, including your timeline and mentions"
+	case ScopeChirpsWrite:
+		return "Post, edit, delete and like chirps as you"
+	case ScopeProfile:
+		return "Edit your profile and who you follow"
+	default:
+		return scope
+	}
+}
+
+// ParseScope splits a space separated OAuth scope parameter. It rejects
+// unknown and empty scopes, and returns the rest without duplicates in the
+// order of Scopes.
+func ParseScope(scope string) ([]string, error) {
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("%w: no scope requested", ErrInvalidScope)
+	}
+	for _, s := range requested {
+		if !slices.Contains(Scopes(), s) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, s)
+		}
+	}
+
+	var scopes []string
+	for _, s := range Scopes() {
+		if slices.Contains(requested, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes, nil
+}
+
+// AccessClaims are the claims of an access token. Tokens issued to an
+// OAuth client name it and the scopes the user granted it. Tokens from
+// logging in to Chirpy directly have neither and may be used for anything.
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+}
+
+// FirstParty reports whether the token came from logging in directly
+// rather than from an OAuth client.
+func (c *AccessClaims) FirstParty() bool {
+	return c.ClientID == ""
+}
+
+// HasScope reports whether the token may be used for scope.
+func (c *AccessClaims) HasScope(scope string) bool {
+	return c.FirstParty() || slices.Contains(strings.Fields(c.Scope), scope)
+}
+
+// MakeClientJWT issues an access token for userID to the OAuth client
+// clientID, limited to scopes.
+func (k *Keyring) MakeClientJWT(
+	userID uuid.UUID,
+	clientID string,
+	scopes []string,
+	expiresIn time.Duration,
+) (string, error) {
+	return k.Sign(&AccessClaims{
+		RegisteredClaims: *accessClaims(userID, expiresIn),
+		ClientID:         clientID,
+		Scope:            strings.Join(scopes, " "),
+	})
+}
+
+// Authorize verifies an access token, or a personal access token if
+// UsePersonalTokens was called, that is to be used for scope and returns
+// its user ID. An empty scope admits only first-party access tokens.
+func (k *Keyring) Authorize(ctx context.Context, tokenString, scope string) (uuid.UUID, error) {
+	if isPersonalToken(tokenString) {
+		return k.authorizePersonalToken(ctx, tokenString, scope)
+	}
+	claims, err := k.ParseAccessToken(ctx, tokenString)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if scope == "" && !claims.FirstParty() || !claims.HasScope(scope) {
+		return uuid.Nil, ErrInsufficientScope
+	}
+	return subjectUserID(&claims.RegisteredClaims)
+}
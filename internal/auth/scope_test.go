@@ -0,0 +1,52 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+)
+
+func TestParseScope(t *testing.T) {
+	scopes, err := auth.ParseScope("profile  chirps:read profile")
+	require.NoError(t, err)
+	assert.Equal(t, []string{auth.ScopeChirpsRead, auth.ScopeProfile}, scopes)
+
+	_, err = auth.ParseScope("chirps:read admin")
+	require.ErrorIs(t, err, auth.ErrInvalidScope)
+	_, err = auth.ParseScope(" ")
+	require.ErrorIs(t, err, auth.ErrInvalidScope)
+}
+
+func TestAuthorize(t *testing.T) {
+	keys := auth.NewHMACKeyring("secret")
+	userID := uuid.New()
+
+	firstParty, err := keys.MakeJWT(userID, time.Minute)
+	require.NoError(t, err)
+	client, err := keys.MakeClientJWT(userID, uuid.NewString(), []string{auth.ScopeChirpsRead}, time.Minute)
+	require.NoError(t, err)
+
+	got, err := keys.Authorize(t.Context(), firstParty, auth.ScopeChirpsWrite)
+	require.NoError(t, err, "first-party tokens should have every scope")
+	assert.Equal(t, userID, got)
+
+	got, err = keys.Authorize(t.Context(), client, auth.ScopeChirpsRead)
+	require.NoError(t, err)
+	assert.Equal(t, userID, got)
+
+	_, err = keys.Authorize(t.Context(), client, auth.ScopeChirpsWrite)
+	require.ErrorIs(t, err, auth.ErrInsufficientScope)
+
+	_, err = keys.ValidateJWT(t.Context(), client)
+	require.ErrorIs(t, err, auth.ErrInsufficientScope, "client tokens should not pass as first-party")
+
+	claims, err := keys.ParseAccessToken(t.Context(), client)
+	require.NoError(t, err)
+	assert.False(t, claims.FirstParty())
+	assert.Equal(t, auth.ScopeChirpsRead, claims.Scope)
+}
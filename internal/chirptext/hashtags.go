@@ -0,0 +1,85 @@
+// Package chirptext finds the entities people write into chirp bodies:
+// hashtags and @mentions.
+package chirptext
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxHashtagLength is the longest hashtag, in runes and without the '#',
+// that is recognised.
+const MaxHashtagLength = 64
+
+// Hashtags returns the distinct hashtags in body, normalised and in the
+// order they first appear. A hashtag is a '#' that does not follow a word
+// character, such as in "issue#3", followed by letters, digits and
+// underscores that are not all digits.
+func Hashtags(body string) []string {
+	var tags []string
+	seen := make(map[string]struct{})
+
+	var prev rune
+	for i := 0; i < len(body); {
+		r, size := utf8.DecodeRuneInString(body[i:])
+		if r != '#' || isWordRune(prev) {
+			prev = r
+			i += size
+			continue
+		}
+
+		end := i + size
+		for end < len(body) {
+			next, nextSize := utf8.DecodeRuneInString(body[end:])
+			if !isWordRune(next) {
+				break
+			}
+			end += nextSize
+		}
+
+		if tag, ok := NormalizeHashtag(body[i+size : end]); ok {
+			if _, dup := seen[tag]; !dup {
+				seen[tag] = struct{}{}
+				tags = append(tags, tag)
+			}
+		}
+
+		prev = '#'
+		if end > i+size {
+			prev, _ = utf8.DecodeLastRuneInString(body[:end])
+		}
+		i = end
+	}
+
+	return tags
+}
+
+// NormalizeHashtag returns the canonical form of a hashtag, with or without
+// its leading '#', so that #Go, #GO and go are the same tag. It reports
+// false if tag is not a valid hashtag.
+func NormalizeHashtag(tag string) (string, bool) {
+	tag = strings.TrimPrefix(tag, "#")
+	if tag == "" || utf8.RuneCountInString(tag) > MaxHashtagLength {
+		return "", false
+	}
+
+	allDigits := true
+	for _, r := range tag {
+		if !isWordRune(r) {
+			return "", false
+		}
+		if !unicode.IsDigit(r) {
+			allDigits = false
+		}
+	}
+	if allDigits {
+		return "", false
+	}
+
+	return strings.ToLower(tag), true
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
@@ -0,0 +1,62 @@
+package chirptext_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Myles-J/chirpy/internal/chirptext"
+)
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []string
+	}{
+		{"No hashtags", "just a chirp", nil},
+		{"Single hashtag", "learning #golang today", []string{"golang"}},
+		{"Normalised to lower case", "#GoLang and #golang", []string{"golang"}},
+		{"Keeps first appearance order", "#b #a #b", []string{"b", "a"}},
+		{"Stops at punctuation", "ship it! #release, #v2.", []string{"release", "v2"}},
+		{"Underscores are part of the tag", "#chirpy_red", []string{"chirpy_red"}},
+		{"Unicode letters", "#café #東京", []string{"café", "東京"}},
+		{"Ignored after word characters", "see issue#3 or a#b", nil},
+		{"All digits are not tags", "#1 and #2024", nil},
+		{"Lone hash", "# heading and ##", nil},
+		{"Adjacent hashtags", "#one#two", []string{"one"}},
+		{"Too long", "#" + strings.Repeat("a", chirptext.MaxHashtagLength+1), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, chirptext.Hashtags(tt.body))
+		})
+	}
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		ok       bool
+	}{
+		{"Bare tag", "Chirpy", "chirpy", true},
+		{"Leading hash", "#Chirpy", "chirpy", true},
+		{"Empty", "", "", false},
+		{"Only hash", "#", "", false},
+		{"Punctuation", "chirpy!", "", false},
+		{"Space", "two words", "", false},
+		{"All digits", "123", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalized, ok := chirptext.NormalizeHashtag(tt.input)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, normalized)
+		})
+	}
+}
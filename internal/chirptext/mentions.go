@@ -0,0 +1,79 @@
+package chirptext
+
+import (
+	"unicode/utf8"
+)
+
+// MaxHandleLength is the longest user handle, without the '@'.
+const MaxHandleLength = 15
+
+// Mention is an @handle written in a chirp. Start and End are character
+// (rune) offsets into the body, End exclusive, and cover the '@'.
+type Mention struct {
+	Handle string
+	Start  int
+	End    int
+}
+
+// Mentions returns every @handle in body in order. Like hashtags, a mention
+// must not follow a word character, so email addresses are not mentions. A
+// run of handle characters longer than MaxHandleLength is not a mention
+// either. Handles are returned as written; they match users case-insensitively.
+func Mentions(body string) []Mention {
+	var mentions []Mention
+
+	var prev rune
+	runeOffset := 0
+	for i := 0; i < len(body); {
+		r, size := utf8.DecodeRuneInString(body[i:])
+		if r != '@' || isWordRune(prev) {
+			prev = r
+			i += size
+			runeOffset++
+			continue
+		}
+
+		end := i + size
+		for end < len(body) {
+			next, nextSize := utf8.DecodeRuneInString(body[end:])
+			if !isWordRune(next) {
+				break
+			}
+			end += nextSize
+		}
+
+		handle := body[i+size : end]
+		length := utf8.RuneCountInString(handle)
+		if IsHandle(handle) {
+			mentions = append(mentions, Mention{
+				Handle: handle,
+				Start:  runeOffset,
+				End:    runeOffset + 1 + length,
+			})
+		}
+
+		prev = '@'
+		if end > i+size {
+			prev, _ = utf8.DecodeLastRuneInString(body[:end])
+		}
+		i = end
+		runeOffset += 1 + length
+	}
+
+	return mentions
+}
+
+// IsHandle reports whether s is a valid user handle: 1 to MaxHandleLength
+// ASCII letters, digits and underscores.
+func IsHandle(s string) bool {
+	if s == "" || len(s) > MaxHandleLength {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
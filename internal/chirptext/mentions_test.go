@@ -0,0 +1,56 @@
+package chirptext_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Myles-J/chirpy/internal/chirptext"
+)
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []chirptext.Mention
+	}{
+		{"No mentions", "hello world", nil},
+		{"Single mention", "hi @alice", []chirptext.Mention{{Handle: "alice", Start: 3, End: 9}}},
+		{"Keeps case and repeats", "@Bob and @bob", []chirptext.Mention{
+			{Handle: "Bob", Start: 0, End: 4},
+			{Handle: "bob", Start: 9, End: 13},
+		}},
+		{"Stops at punctuation", "thanks @carol!", []chirptext.Mention{{Handle: "carol", Start: 7, End: 13}}},
+		{"Offsets count characters not bytes", "café @dave", []chirptext.Mention{{Handle: "dave", Start: 5, End: 10}}},
+		{"Email addresses are not mentions", "mail me at eve@example.com", nil},
+		{"Lone at sign", "meet @ noon", nil},
+		{"Too long", "@" + strings.Repeat("a", chirptext.MaxHandleLength+1), nil},
+		{"Non-ASCII handle", "@josé", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, chirptext.Mentions(tt.body))
+		})
+	}
+}
+
+func TestIsHandle(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"alice", true},
+		{"Alice_99", true},
+		{"", false},
+		{"has space", false},
+		{"dash-ed", false},
+		{strings.Repeat("a", chirptext.MaxHandleLength), true},
+		{strings.Repeat("a", chirptext.MaxHandleLength+1), false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, chirptext.IsHandle(tt.input), tt.input)
+	}
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// ChirpSearch is a search over the chirps table assembled at runtime. Where,
+// Rank and Snippet are SQL fragments whose $n placeholders refer to Args.
+type ChirpSearch struct {
+	Where   string
+	Rank    string
+	Snippet string
+	Args    []interface{}
+}
+
+type SearchChirpsRow struct {
+	Chirp   Chirp
+	Rank    float32
+	Snippet string
+}
+
+// SearchChirps runs a ChirpSearch, most relevant chirps first. This query is
+// written by hand because its filters are only known once the search has been
+// parsed, so it cannot be generated by sqlc.
+func (q *Queries) SearchChirps(
+	ctx context.Context,
+	search ChirpSearch,
+	pageLimit int32,
+	pageOffset int32,
+) ([]SearchChirpsRow, error) {
+	args := append(search.Args[:len(search.Args):len(search.Args)], pageLimit, pageOffset)
+	query := fmt.Sprintf(`SELECT
+    id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, like_count, rechirp_of, quote_of, edited_at,
+    (%s)::real AS rank,
+    %s AS snippet
+FROM chirps
+WHERE %s
+ORDER BY rank DESC, created_at DESC, id DESC
+LIMIT $%d OFFSET $%d`, search.Rank, search.Snippet, search.Where, len(args)-1, len(args))
+
+	rows, err := q.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.InReplyTo,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.LikeCount,
+			&i.Chirp.RechirpOf,
+			&i.Chirp.QuoteOf,
+			&i.Chirp.EditedAt,
+			&i.Rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
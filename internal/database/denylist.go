@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Denylist is an access token denylist stored in Postgres, so every server
+// instance sees the same revocations. Times are stored as UTC to match the
+// TIMESTAMP columns.
+type Denylist struct {
+	q *Queries
+}
+
+// NewDenylist returns a Denylist backed by q.
+func NewDenylist(q *Queries) *Denylist {
+	return &Denylist{q: q}
+}
+
+// RevokeToken rejects the token with jti until expiresAt, clearing out
+// entries for tokens that have since expired.
+func (d *Denylist) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if err := d.q.DeleteExpiredRevokedAccessTokens(ctx); err != nil {
+		return err
+	}
+	return d.q.RevokeAccessToken(ctx, RevokeAccessTokenParams{Jti: jti, ExpiresAt: expiresAt.UTC()})
+}
+
+// RevokeUserTokens rejects every token for userID issued before notBefore.
+func (d *Denylist) RevokeUserTokens(ctx context.Context, userID uuid.UUID, notBefore time.Time) error {
+	return d.q.SetAccessTokenCutoff(ctx, SetAccessTokenCutoffParams{UserID: userID, NotBefore: notBefore.UTC()})
+}
+
+// IsRevoked reports whether the token was revoked by jti or by a cutoff.
+func (d *Denylist) IsRevoked(ctx context.Context, jti string, userID uuid.UUID, issuedAt time.Time) (bool, error) {
+	return d.q.IsAccessTokenRevoked(ctx, IsAccessTokenRevokedParams{
+		Jti:      jti,
+		UserID:   userID,
+		IssuedAt: issuedAt.UTC(),
+	})
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Myles-J/chirpy/internal/auth"
+)
+
+// PersonalTokens looks up personal access tokens stored in Postgres.
+type PersonalTokens struct {
+	q *Queries
+}
+
+// NewPersonalTokens returns a PersonalTokens backed by q.
+func NewPersonalTokens(q *Queries) *PersonalTokens {
+	return &PersonalTokens{q: q}
+}
+
+// LookupPersonalToken returns the token with tokenHash and notes that it
+// was used.
+func (p *PersonalTokens) LookupPersonalToken(ctx context.Context, tokenHash string) (auth.PersonalToken, error) {
+	token, err := p.q.GetPersonalAccessTokenByHash(ctx, tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return auth.PersonalToken{}, auth.ErrInvalidPersonalToken
+	}
+	if err != nil {
+		return auth.PersonalToken{}, err
+	}
+	if err = p.q.TouchPersonalAccessToken(ctx, token.ID); err != nil {
+		return auth.PersonalToken{}, err
+	}
+
+	personal := auth.PersonalToken{UserID: token.UserID, Scopes: token.Scopes}
+	if token.ExpiresAt.Valid {
+		personal.ExpiresAt = token.ExpiresAt.Time
+	}
+	return personal, nil
+}
@@ -0,0 +1,22 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// InTx runs fn against a copy of q bound to a new transaction on conn. The
+// transaction is committed if fn returns nil and rolled back otherwise.
+func (q *Queries) InTx(ctx context.Context, conn *sql.DB, fn func(*Queries) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if fnErr := fn(q.WithTx(tx)); fnErr != nil {
+		return errors.Join(fnErr, tx.Rollback())
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,167 @@
+package mail_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Myles-J/chirpy/internal/mail"
+)
+
+// smtpStandIn is a minimal SMTP server that accepts one message and
+// records the envelope and data it received.
+type smtpStandIn struct {
+	addr     string
+	received chan receivedMail
+}
+
+type receivedMail struct {
+	from string
+	to   string
+	data string
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	s := &smtpStandIn{addr: listener.Addr().String(), received: make(chan receivedMail, 1)}
+	go func() {
+		conn, acceptErr := listener.Accept()
+		if acceptErr != nil {
+			return
+		}
+		defer conn.Close()
+		s.serve(conn)
+	}()
+	return s
+}
+
+func (s *smtpStandIn) serve(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+	var msg receivedMail
+	reply("220 localhost ESMTP stand-in")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			msg.from = strings.TrimPrefix(line, "MAIL FROM:")
+			reply("250 OK")
+		case "RCPT":
+			msg.to = strings.TrimPrefix(line, "RCPT TO:")
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, dataErr := r.ReadString('\n')
+				if dataErr != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			msg.data = data.String()
+			s.received <- msg
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	server := newSMTPStandIn(t)
+	mailer := mail.NewSMTPMailer(server.addr, "no-reply@chirpy.test", "", "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := mailer.Send(ctx, mail.Message{
+		To:      "bob@example.com",
+		Subject: "Verify your email",
+		Body:    "Your code is 1234.\nThanks!",
+	})
+	require.NoError(t, err)
+
+	select {
+	case got := <-server.received:
+		assert.Equal(t, "<no-reply@chirpy.test>", got.from)
+		assert.Equal(t, "<bob@example.com>", got.to)
+		assert.Contains(t, got.data, "To: bob@example.com\r\n")
+		assert.Contains(t, got.data, "Subject: Verify your email\r\n")
+		assert.Contains(t, got.data, "\r\n\r\nYour code is 1234.\r\nThanks!\r\n")
+	case <-ctx.Done():
+		t.Fatal("stand-in server did not receive a message")
+	}
+}
+
+func TestSMTPMailer_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	mailer := mail.NewSMTPMailer(addr, "no-reply@chirpy.test", "", "")
+	err = mailer.Send(context.Background(), mail.Message{To: "bob@example.com", Subject: "Hi", Body: "Hi"})
+	require.Error(t, err)
+}
+
+func TestLogMailer_Send(t *testing.T) {
+	var out bytes.Buffer
+	mailer := mail.NewLogMailer("no-reply@chirpy.test", &out)
+
+	err := mailer.Send(context.Background(), mail.Message{
+		To:      "bob@example.com",
+		Subject: "Hello",
+		Body:    "Hi Bob",
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, out.String(), "From: no-reply@chirpy.test\r\n")
+	assert.Contains(t, out.String(), "To: bob@example.com\r\n")
+	assert.Contains(t, out.String(), "Hi Bob")
+}
+
+func TestSend_RejectsHeaderInjection(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  mail.Message
+	}{
+		{"Recipient", mail.Message{To: "bob@example.com\r\nBcc: eve@example.com", Subject: "Hi"}},
+		{"Subject", mail.Message{To: "bob@example.com", Subject: "Hi\nBcc: eve@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := mail.NewLogMailer("no-reply@chirpy.test", &out).Send(context.Background(), tt.msg)
+			require.ErrorIs(t, err, mail.ErrInvalidHeader)
+			assert.Empty(t, out.String())
+		})
+	}
+}
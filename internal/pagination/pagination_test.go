@@ -0,0 +1,75 @@
+package pagination_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Myles-J/chirpy/internal/pagination"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := pagination.Cursor{
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 123456000, time.UTC),
+		ID:        uuid.New(),
+	}
+
+	decoded, err := pagination.DecodeCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.True(t, cursor.CreatedAt.Equal(decoded.CreatedAt))
+	assert.Equal(t, cursor.ID, decoded.ID)
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"Not base64", "%%%"},
+		{"Missing separator", "bm90LWEtY3Vyc29y"},
+		{"Bad timestamp", "eWVzdGVyZGF5LDEyM2U0NTY3LWU4OWItMTJkMy1hNDU2LTQyNjYxNDE3NDAwMA"},
+	}
+
+	for _, tt := range tests {
+		_, err := pagination.DecodeCursor(tt.cursor)
+		require.ErrorIs(t, err, pagination.ErrInvalidCursor, tt.name)
+	}
+}
+
+func TestOffsetRoundTrip(t *testing.T) {
+	offset, err := pagination.DecodeOffset(pagination.EncodeOffset(40))
+	require.NoError(t, err)
+	assert.Equal(t, int32(40), offset)
+
+	_, err = pagination.DecodeOffset(pagination.EncodeOffset(-1))
+	require.ErrorIs(t, err, pagination.ErrInvalidCursor)
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  int32
+		expectErr bool
+	}{
+		{"Empty uses default", "", pagination.DefaultLimit, false},
+		{"Valid limit", "50", 50, false},
+		{"Maximum limit", "100", pagination.MaxLimit, false},
+		{"Zero", "0", 0, true},
+		{"Too large", "101", 0, true},
+		{"Not a number", "ten", 0, true},
+	}
+
+	for _, tt := range tests {
+		limit, err := pagination.ParseLimit(tt.input)
+		if tt.expectErr {
+			require.ErrorIs(t, err, pagination.ErrInvalidLimit, tt.name)
+			continue
+		}
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.expected, limit, tt.name)
+	}
+}
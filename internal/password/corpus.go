@@ -0,0 +1,86 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1" //nolint:gosec // breach corpora such as Pwned Passwords are keyed by SHA-1
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// corpusPrefixLength is the number of hex digits of a hash used to pick its
+// range, as in the Pwned Passwords range API.
+const corpusPrefixLength = 5
+
+var ErrInvalidCorpus = errors.New("invalid breached password corpus")
+
+// Corpus is a set of breached passwords, held as upper case hex SHA-1
+// hashes grouped by their first five digits. A lookup only touches the
+// range for its prefix, the same k-anonymity layout the Pwned Passwords
+// API serves, so a corpus can be trimmed from or swapped for that one.
+type Corpus struct {
+	ranges map[string][]string
+	size   int
+}
+
+// OpenCorpus loads a corpus from the file at path.
+func OpenCorpus(path string) (*Corpus, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadCorpus(f)
+}
+
+// LoadCorpus reads a corpus with one SHA-1 hash per line, optionally
+// followed by ":" and a count as in the Pwned Passwords downloads. Blank
+// lines and lines starting with "#" are skipped.
+func LoadCorpus(r io.Reader) (*Corpus, error) {
+	corpus := &Corpus{ranges: make(map[string][]string)}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(text, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != 2*sha1.Size {
+			return nil, fmt.Errorf("%w: line %d is not a SHA-1 hash", ErrInvalidCorpus, line)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("%w: line %d is not a SHA-1 hash", ErrInvalidCorpus, line)
+		}
+		prefix := hash[:corpusPrefixLength]
+		corpus.ranges[prefix] = append(corpus.ranges[prefix], hash[corpusPrefixLength:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for prefix, suffixes := range corpus.ranges {
+		slices.Sort(suffixes)
+		suffixes = slices.Compact(suffixes)
+		corpus.ranges[prefix] = suffixes
+		corpus.size += len(suffixes)
+	}
+	return corpus, nil
+}
+
+// Len returns the number of distinct passwords in c.
+func (c *Corpus) Len() int {
+	return c.size
+}
+
+// Contains reports whether password is in c.
+func (c *Corpus) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password)) //nolint:gosec // see the import
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	_, found := slices.BinarySearch(c.ranges[hash[:corpusPrefixLength]], hash[corpusPrefixLength:])
+	return found
+}
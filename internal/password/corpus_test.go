@@ -0,0 +1,45 @@
+package password_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Myles-J/chirpy/internal/password"
+)
+
+func TestCorpus(t *testing.T) {
+	// SHA-1 of "password" with a count, of "hunter2" in lower case without
+	// one, and a duplicate.
+	corpus, err := password.LoadCorpus(strings.NewReader(`
+# breached passwords
+5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824
+f3bbbd66a63d4bf1747940578ec3d0103530e21d
+5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:1
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, corpus.Len())
+	assert.True(t, corpus.Contains("password"))
+	assert.True(t, corpus.Contains("hunter2"))
+	assert.False(t, corpus.Contains("Password"))
+	assert.False(t, corpus.Contains("correct horse battery staple"))
+}
+
+func TestLoadCorpusRejectsBadLines(t *testing.T) {
+	for _, input := range []string{
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD",
+		"ZBAA61E4C9B93F3F0682250B6CF8331B7EE68FD8",
+		"password",
+	} {
+		_, err := password.LoadCorpus(strings.NewReader(input))
+		require.ErrorIs(t, err, password.ErrInvalidCorpus, input)
+	}
+}
+
+func TestOpenCorpus(t *testing.T) {
+	_, err := password.OpenCorpus(t.TempDir() + "/missing.txt")
+	require.Error(t, err)
+}
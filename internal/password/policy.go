@@ -0,0 +1,90 @@
+// Package password decides whether a new password is acceptable: long
+// enough, hard enough to guess, not the user's email address and not known
+// from a data breach.
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// The rules a password can break, as reported in Violation.Rule.
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleStrength  = "strength"
+	RuleNotEmail  = "not_email"
+	RuleBreached  = "not_breached"
+)
+
+// Violation is one rule a password breaks.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Policy is what new passwords must satisfy. Zero fields are not checked.
+type Policy struct {
+	// MinLength and MaxLength bound the length in characters.
+	MinLength int
+	MaxLength int
+	// MinScore is the lowest acceptable Estimate score, from 0 to 4.
+	MinScore int
+	// Breached, if set, holds passwords that may not be used.
+	Breached *Corpus
+}
+
+// Check returns every rule password breaks for the account with email, or
+// nil if it is acceptable.
+func (p Policy) Check(password, email string) []Violation {
+	var violations []Violation
+	length := utf8.RuneCountInString(password)
+
+	if length < p.MinLength {
+		violations = append(violations, Violation{
+			Rule:    RuleMinLength,
+			Message: fmt.Sprintf("Password must be at least %d characters long.", p.MinLength),
+		})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, Violation{
+			Rule:    RuleMaxLength,
+			Message: fmt.Sprintf("Password must be at most %d characters long.", p.MaxLength),
+		})
+		// Estimating a very long password is slow and pointless.
+		return violations
+	}
+
+	if isEmail(password, email) {
+		violations = append(violations, Violation{
+			Rule:    RuleNotEmail,
+			Message: "Password must not be your email address.",
+		})
+	}
+	if p.MinScore > 0 && Estimate(password, email).Score < p.MinScore {
+		violations = append(violations, Violation{
+			Rule:    RuleStrength,
+			Message: "Password is too easy to guess. Try a longer phrase of uncommon words.",
+		})
+	}
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violations = append(violations, Violation{
+			Rule:    RuleBreached,
+			Message: "Password has appeared in a data breach. Please choose another.",
+		})
+	}
+	return violations
+}
+
+// isEmail reports whether password is email or the part before the "@",
+// ignoring case and surrounding space.
+func isEmail(password, email string) bool {
+	password = strings.ToLower(strings.TrimSpace(password))
+	email = strings.ToLower(strings.TrimSpace(email))
+	if password == "" || email == "" {
+		return false
+	}
+	local, _, _ := strings.Cut(email, "@")
+	return password == email || password == local
+}
@@ -0,0 +1,54 @@
+package password_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Myles-J/chirpy/internal/password"
+)
+
+func rules(violations []password.Violation) []string {
+	var names []string
+	for _, v := range violations {
+		names = append(names, v.Rule)
+	}
+	return names
+}
+
+func TestPolicyCheck(t *testing.T) {
+	corpus, err := password.LoadCorpus(strings.NewReader("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\n"))
+	require.NoError(t, err)
+	policy := password.Policy{MinLength: 8, MaxLength: 64, MinScore: 3, Breached: corpus}
+	const email = "Jane.Doe@example.com"
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{"acceptable", "purple-monkey-dishwasher", nil},
+		{"empty", "", []string{password.RuleMinLength, password.RuleStrength}},
+		{"one character", "x", []string{password.RuleMinLength, password.RuleStrength}},
+		{"breached", "password", []string{password.RuleStrength, password.RuleBreached}},
+		{"email", "jane.doe@example.com", []string{password.RuleNotEmail, password.RuleStrength}},
+		{"email local part", " JANE.DOE ", []string{password.RuleNotEmail, password.RuleStrength}},
+		{"too long", strings.Repeat("x", 65), []string{password.RuleMaxLength}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := policy.Check(tt.password, email)
+			assert.Equal(t, tt.want, rules(violations))
+			for _, v := range violations {
+				assert.NotEmpty(t, v.Message)
+			}
+		})
+	}
+}
+
+func TestZeroPolicyAcceptsAnything(t *testing.T) {
+	assert.Empty(t, password.Policy{}.Check("", "jane@example.com"))
+	assert.Empty(t, password.Policy{}.Check("x", ""))
+}
@@ -0,0 +1,318 @@
+package password
+
+import (
+	_ "embed"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// commonPasswords lists frequently used passwords and words, most common
+// first.
+//
+//go:embed common_passwords.txt
+var commonPasswords string
+
+const (
+	// bruteforceLog10 is log10 of the guesses per character that no
+	// pattern explains, as in zxcvbn.
+	bruteforceLog10 = 1.0
+	// minMatchLength is the shortest dictionary, sequence or repeat match.
+	minMatchLength = 3
+	// minKeyboardLength is the shortest run of keys along a keyboard row
+	// treated as a pattern.
+	minKeyboardLength = 4
+	// maxWordLength bounds the substrings looked up in the dictionary.
+	maxWordLength = 24
+)
+
+// scoreThresholds are the log10 guesses a password needs for each score
+// above zero.
+func scoreThresholds() []float64 {
+	return []float64{3, 6, 8, 10}
+}
+
+// keyboardRows are the rows of a US QWERTY keyboard.
+func keyboardRows() []string {
+	return []string{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./"}
+}
+
+// leetSubstitutions maps common character substitutions back to letters.
+func leetSubstitutions() map[rune]rune {
+	return map[rune]rune{
+		'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'i',
+		'!': 'i', '|': 'i', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z',
+	}
+}
+
+// Strength estimates how many guesses an attacker who knows common
+// passwords and patterns would need to find a password.
+type Strength struct {
+	// Log10Guesses is log10 of the estimated number of guesses.
+	Log10Guesses float64
+	// Score rates the password from 0, trivially guessable, to 4, very
+	// unguessable, on the same scale as zxcvbn.
+	Score int
+}
+
+// match is a pattern covering runes [start, end) of a password.
+type match struct {
+	start, end int
+	log10      float64
+}
+
+// Estimate rates password in the style of zxcvbn: it finds dictionary
+// words, l33t spellings, sequences, repeats and keyboard runs, and takes
+// the cheapest way to build the password from them and single characters.
+// userInputs, such as the user's email address, count as very common words.
+func Estimate(password string, userInputs ...string) Strength {
+	guesses := estimateLog10([]rune(password), rankedWords(userInputs))
+	score := 0
+	for _, threshold := range scoreThresholds() {
+		if guesses >= threshold {
+			score++
+		}
+	}
+	return Strength{Log10Guesses: guesses, Score: score}
+}
+
+// estimateLog10 returns log10 of the guesses needed for runes.
+func estimateLog10(runes []rune, dictionary map[string]int) float64 {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	var matches []match
+	matches = append(matches, dictionaryMatches(runes, lower, dictionary)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, repeatMatches(runes, dictionary)...)
+	matches = append(matches, keyboardMatches(lower)...)
+
+	byEnd := make([][]match, len(runes)+1)
+	for _, m := range matches {
+		byEnd[m.end] = append(byEnd[m.end], m)
+	}
+
+	best := make([]float64, len(runes)+1)
+	for i := 1; i <= len(runes); i++ {
+		best[i] = best[i-1] + bruteforceLog10
+		for _, m := range byEnd[i] {
+			best[i] = min(best[i], best[m.start]+m.log10)
+		}
+	}
+
+	return best[len(runes)]
+}
+
+// rankedWords returns the common password list and the words of
+// userInputs, each mapped to its rank. User inputs rank first.
+func rankedWords(userInputs []string) map[string]int {
+	ranks := make(map[string]int)
+	add := func(word string) {
+		if len([]rune(word)) < minMatchLength {
+			return
+		}
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = len(ranks) + 1
+		}
+	}
+
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		add(input)
+		for _, word := range strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			add(word)
+		}
+	}
+	for line := range strings.Lines(commonPasswords) {
+		add(strings.TrimSpace(line))
+	}
+	return ranks
+}
+
+// dictionaryMatches finds words from dictionary, including reversed and
+// l33t spellings. Capital letters and substitutions make a word only a
+// little harder to guess.
+func dictionaryMatches(runes, lower []rune, dictionary map[string]int) []match {
+	leet := leetSubstitutions()
+	var matches []match
+	for i := range lower {
+		for j := i + minMatchLength; j <= len(lower) && j-i <= maxWordLength; j++ {
+			word := lower[i:j]
+			unleeted := make([]rune, len(word))
+			substitutions := 0
+			for k, r := range word {
+				if sub, ok := leet[r]; ok {
+					unleeted[k] = sub
+					substitutions++
+				} else {
+					unleeted[k] = r
+				}
+			}
+			reversed := slices.Clone(word)
+			slices.Reverse(reversed)
+
+			extra := uppercaseLog10(runes[i:j])
+			candidates := []struct {
+				word  string
+				extra float64
+			}{
+				{string(word), extra},
+				{string(reversed), extra + math.Log10(2)},
+			}
+			if substitutions > 0 {
+				candidates = append(candidates, struct {
+					word  string
+					extra float64
+				}{string(unleeted), extra + float64(substitutions)*math.Log10(2)})
+			}
+			for _, c := range candidates {
+				if rank, ok := dictionary[c.word]; ok {
+					matches = append(matches, match{start: i, end: j, log10: math.Log10(float64(rank)) + c.extra})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// uppercaseLog10 is the extra guesses for the capitalisation of word:
+// none if it is all lower case, one bit if only the first or every letter
+// is upper case, and a bit per capital otherwise.
+func uppercaseLog10(word []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range word {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 0
+	case lower == 0, upper == 1 && unicode.IsUpper(word[0]):
+		return math.Log10(2)
+	default:
+		return float64(min(upper, lower)+1) * math.Log10(2)
+	}
+}
+
+// sequenceMatches finds runs like "abc", "7654" or "XYZ" where each
+// character follows the last in the same direction.
+func sequenceMatches(runes []rune) []match {
+	var matches []match
+	for i := 0; i < len(runes)-1; {
+		delta := runes[i+1] - runes[i]
+		j := i + 1
+		if delta == 1 || delta == -1 {
+			for j < len(runes) && runes[j]-runes[j-1] == delta && charClass(runes[j]) == charClass(runes[i]) {
+				j++
+			}
+		}
+		if j-i >= minMatchLength {
+			base := 26.0
+			switch {
+			case strings.ContainsRune("aAzZ019", runes[i]):
+				base = 4
+			case unicode.IsDigit(runes[i]):
+				base = 10
+			}
+			log10 := math.Log10(base * float64(j-i))
+			if delta < 0 {
+				log10 += math.Log10(2)
+			}
+			matches = append(matches, match{start: i, end: j, log10: log10})
+			i = j
+			continue
+		}
+		i++
+	}
+	return matches
+}
+
+// repeatMatches finds a character or block of characters repeated, like
+// "aaaa" or "abcabc". A repeat is as easy to guess as one copy times the
+// number of copies.
+func repeatMatches(runes []rune, dictionary map[string]int) []match {
+	var matches []match
+	for i := range runes {
+		for size := 1; i+2*size <= len(runes); size++ {
+			block := runes[i : i+size]
+			end := i + size
+			for end+size <= len(runes) && slices.Equal(runes[end:end+size], block) {
+				end += size
+			}
+			copies := (end - i) / size
+			if copies < 2 || end-i < minMatchLength {
+				continue
+			}
+			blockLog10 := math.Log10(charClassSize(block[0]))
+			if size > 1 {
+				blockLog10 = estimateLog10(block, dictionary)
+			}
+			matches = append(matches, match{start: i, end: end, log10: blockLog10 + math.Log10(float64(copies))})
+		}
+	}
+	return matches
+}
+
+// keyboardMatches finds runs of neighbouring keys along a keyboard row, in
+// either direction, like "qwerty" or "lkjh".
+func keyboardMatches(lower []rune) []match {
+	keyboard := keyboardRows()
+	var rows []string
+	for _, row := range keyboard {
+		reversed := []rune(row)
+		slices.Reverse(reversed)
+		rows = append(rows, row, string(reversed))
+	}
+
+	var matches []match
+	for i := range lower {
+		longest := 0
+		for j := i + minKeyboardLength; j <= len(lower); j++ {
+			run := string(lower[i:j])
+			if !slices.ContainsFunc(rows, func(row string) bool { return strings.Contains(row, run) }) {
+				break
+			}
+			longest = j - i
+		}
+		if longest > 0 {
+			starts := float64(len(rows) * len(keyboard[0]))
+			matches = append(matches, match{start: i, end: i + longest, log10: math.Log10(starts * float64(longest))})
+		}
+	}
+	return matches
+}
+
+// charClass groups runes into lower case, upper case, digits and others.
+func charClass(r rune) int {
+	switch {
+	case unicode.IsLower(r):
+		return 1
+	case unicode.IsUpper(r):
+		return 2
+	case unicode.IsDigit(r):
+		return 3
+	default:
+		return 0
+	}
+}
+
+// charClassSize is the number of characters in r's class.
+func charClassSize(r rune) float64 {
+	switch charClass(r) {
+	case 1, 2:
+		return 26
+	case 3:
+		return 10
+	default:
+		return 33
+	}
+}
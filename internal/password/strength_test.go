@@ -0,0 +1,52 @@
+package password_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Myles-J/chirpy/internal/password"
+)
+
+func TestEstimateWeakPasswords(t *testing.T) {
+	for _, weak := range []string{
+		"",
+		"password",
+		"Password1",
+		"p4ssw0rd",
+		"drowssap",
+		"qwerty123",
+		"asdfghjkl",
+		"abcdefgh",
+		"9876543210",
+		"aaaaaaaaaaaa",
+		"abcabcabcabc",
+		"monkeymonkey",
+	} {
+		assert.LessOrEqual(t, password.Estimate(weak).Score, 1, "%q", weak)
+	}
+}
+
+func TestEstimateStrongPasswords(t *testing.T) {
+	for _, strong := range []string{
+		"correct horse battery staple",
+		"purple-monkey-dishwasher",
+		"j8#Kp2!vQz",
+	} {
+		assert.Equal(t, 4, password.Estimate(strong).Score, "%q", strong)
+	}
+}
+
+func TestEstimateUserInputs(t *testing.T) {
+	without := password.Estimate("janedoe1987")
+	with := password.Estimate("janedoe1987", "janedoe@example.com")
+
+	assert.Less(t, with.Log10Guesses, without.Log10Guesses, "the user's own details should be easy to guess")
+	assert.Less(t, with.Score, 3)
+}
+
+func TestEstimateIsMonotonicInLength(t *testing.T) {
+	short := password.Estimate("k7#Qm")
+	long := password.Estimate("k7#Qm2$vLp9!")
+	assert.Greater(t, long.Log10Guesses, short.Log10Guesses)
+}
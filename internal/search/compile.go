@@ -0,0 +1,94 @@
+package search
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/Myles-J/chirpy/internal/database"
+)
+
+const (
+	textSearchConfig = "'english'"
+	// searchVector must match the expression chirps_search_idx indexes.
+	searchVector    = "to_tsvector(" + textSearchConfig + ", body)"
+	headlineOptions = "'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'"
+	linkPattern     = `'https?://\S+'`
+	// escapedBody is the chirp body as HTML text, so the only markup in a
+	// snippet is the <mark> tags ts_headline adds.
+	escapedBody = "replace(replace(replace(replace(replace(body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), " +
+		`'"', '&quot;'), '''', '&#39;')`
+)
+
+// Compile turns the query into parameterised SQL over the chirps table.
+// User input only ever reaches the database as arguments.
+func (q *Query) Compile() database.ChirpSearch {
+	b := &builder{}
+
+	var matches []string
+	for _, word := range q.Words {
+		matches = append(matches, "plainto_tsquery("+textSearchConfig+", "+b.arg(word)+")")
+	}
+	for _, phrase := range q.Phrases {
+		matches = append(matches, "phraseto_tsquery("+textSearchConfig+", "+b.arg(phrase)+")")
+	}
+	// An excluded stopword normalises to an empty tsquery, which matches
+	// nothing, so its negation would hide every chirp. Such terms are skipped.
+	excludes := make([]string, 0, len(q.Excluded))
+	for _, excluded := range q.Excluded {
+		exclude := "phraseto_tsquery(" + textSearchConfig + ", " + b.arg(excluded) + ")"
+		excludes = append(excludes, "(numnode("+exclude+") = 0 OR NOT "+searchVector+" @@ "+exclude+")")
+	}
+
+	conditions := []string{"deleted_at IS NULL"}
+	search := database.ChirpSearch{
+		Rank:    "0",
+		Snippet: escapedBody,
+	}
+
+	if len(matches) > 0 {
+		match := "(" + strings.Join(matches, " && ") + ")"
+		search.Rank = "ts_rank(" + searchVector + ", " + match + ")"
+		search.Snippet = "ts_headline(" + textSearchConfig + ", " + escapedBody + ", " + match + ", " + headlineOptions + ")"
+		conditions = append(conditions, searchVector+" @@ "+match)
+	}
+	conditions = append(conditions, excludes...)
+
+	if len(q.From) > 0 {
+		authors := make([]string, 0, len(q.From))
+		for _, from := range q.From {
+			if id, err := uuid.Parse(from); err == nil {
+				authors = append(authors, "user_id = "+b.arg(id))
+			} else {
+				authors = append(authors, "user_id IN (SELECT id FROM users WHERE lower(username) = lower("+b.arg(from)+"))")
+			}
+		}
+		conditions = append(conditions, "("+strings.Join(authors, " OR ")+")")
+	}
+
+	if !q.Since.IsZero() {
+		conditions = append(conditions, "created_at >= "+b.arg(q.Since))
+	}
+	if !q.Until.IsZero() {
+		conditions = append(conditions, "created_at < "+b.arg(q.Until.AddDate(0, 0, 1)))
+	}
+
+	if q.HasLink {
+		conditions = append(conditions, "body ~* "+linkPattern)
+	}
+
+	search.Where = strings.Join(conditions, "\n  AND ")
+	search.Args = b.args
+	return search
+}
+
+type builder struct {
+	args []interface{}
+}
+
+// arg records a query argument and returns its placeholder.
+func (b *builder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return "$" + strconv.Itoa(len(b.args))
+}
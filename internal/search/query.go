@@ -0,0 +1,192 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const dateLayout = "2006-01-02"
+
+var ErrEmptyQuery = errors.New("search query is empty")
+
+// SyntaxError reports a token the parser could not understand. Pos is the
+// byte offset of the token in the original query.
+type SyntaxError struct {
+	Pos    int
+	Token  string
+	Reason string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at offset %d: %q", e.Reason, e.Pos, e.Token)
+}
+
+// Query is a parsed search. Words and Phrases must all match, Excluded words
+// and phrases must not, and a chirp must be written by one of From if any are given.
+type Query struct {
+	Words    []string
+	Phrases  []string
+	Excluded []string
+	From     []string
+	Since    time.Time
+	Until    time.Time
+	HasLink  bool
+}
+
+// Parse parses a search query. Besides plain words it understands:
+//
+//	"quoted phrase"   the words must appear next to each other
+//	-word, -"phrase"  the word or phrase must not appear
+//	from:<user>       written by the user with this ID or username
+//	since:YYYY-MM-DD  written on or after this date (UTC)
+//	until:YYYY-MM-DD  written on or before this date (UTC)
+//	has:link          the chirp contains a link
+func Parse(input string) (*Query, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, ErrEmptyQuery
+	}
+
+	query := &Query{}
+	for _, tok := range tokens {
+		if err := query.add(tok); err != nil {
+			return nil, err
+		}
+	}
+
+	if !query.Since.IsZero() && !query.Until.IsZero() && query.Until.Before(query.Since) {
+		return nil, &SyntaxError{Pos: 0, Token: input, Reason: "until: is before since:"}
+	}
+
+	return query, nil
+}
+
+type token struct {
+	pos     int
+	raw     string
+	value   string
+	negated bool
+	quoted  bool
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		if isSpace(input[i]) {
+			i++
+			continue
+		}
+
+		tok := token{pos: i}
+		start := i
+		if input[i] == '-' {
+			tok.negated = true
+			i++
+			if i == len(input) || isSpace(input[i]) {
+				return nil, &SyntaxError{Pos: start, Token: "-", Reason: "nothing to exclude"}
+			}
+		}
+
+		if input[i] == '"' {
+			end := strings.IndexByte(input[i+1:], '"')
+			if end < 0 {
+				return nil, &SyntaxError{Pos: start, Token: input[start:], Reason: "unterminated quote"}
+			}
+			tok.quoted = true
+			tok.value = strings.TrimSpace(input[i+1 : i+1+end])
+			i += end + 2
+			if tok.value == "" {
+				return nil, &SyntaxError{Pos: start, Token: input[start:i], Reason: "empty phrase"}
+			}
+		} else {
+			for i < len(input) && !isSpace(input[i]) {
+				i++
+			}
+			tok.value = input[start:i]
+			if tok.negated {
+				tok.value = tok.value[1:]
+			}
+		}
+
+		tok.raw = input[start:i]
+		tokens = append(tokens, tok)
+	}
+	return tokens, nil
+}
+
+func (q *Query) add(tok token) error {
+	if tok.quoted {
+		if tok.negated {
+			q.Excluded = append(q.Excluded, tok.value)
+		} else {
+			q.Phrases = append(q.Phrases, tok.value)
+		}
+		return nil
+	}
+
+	name, value, isOperator := splitOperator(tok.value)
+	if !isOperator {
+		if tok.negated {
+			q.Excluded = append(q.Excluded, tok.value)
+		} else {
+			q.Words = append(q.Words, tok.value)
+		}
+		return nil
+	}
+
+	if tok.negated {
+		return &SyntaxError{Pos: tok.pos, Token: tok.raw, Reason: "operators cannot be excluded"}
+	}
+	if value == "" {
+		return &SyntaxError{Pos: tok.pos, Token: tok.raw, Reason: "missing value for " + name + ":"}
+	}
+
+	switch name {
+	case "from":
+		q.From = append(q.From, value)
+	case "since", "until":
+		date, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return &SyntaxError{Pos: tok.pos, Token: tok.raw, Reason: "dates must be formatted as YYYY-MM-DD"}
+		}
+		if name == "since" {
+			q.Since = date
+		} else {
+			q.Until = date
+		}
+	case "has":
+		if value != "link" {
+			return &SyntaxError{Pos: tok.pos, Token: tok.raw, Reason: "has: only supports link"}
+		}
+		q.HasLink = true
+	default:
+		return &SyntaxError{Pos: tok.pos, Token: tok.raw, Reason: "unknown operator " + name + ":"}
+	}
+	return nil
+}
+
+// splitOperator reports whether word has the form name:value. Links such as
+// https://example.com are treated as plain words.
+func splitOperator(word string) (string, string, bool) {
+	name, value, found := strings.Cut(word, ":")
+	if !found || name == "" || strings.HasPrefix(value, "//") {
+		return "", "", false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) {
+			return "", "", false
+		}
+	}
+	return strings.ToLower(name), value, true
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
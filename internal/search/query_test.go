@@ -0,0 +1,121 @@
+package search_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Myles-J/chirpy/internal/search"
+)
+
+func TestParse(t *testing.T) {
+	query, err := search.Parse(`gopher "hello world" -spam -"buy now" from:bob@example.com ` +
+		`since:2026-01-01 until:2026-02-01 has:link https://example.com`)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"gopher", "https://example.com"}, query.Words)
+	assert.Equal(t, []string{"hello world"}, query.Phrases)
+	assert.Equal(t, []string{"spam", "buy now"}, query.Excluded)
+	assert.Equal(t, []string{"bob@example.com"}, query.From)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), query.Since)
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), query.Until)
+	assert.True(t, query.HasLink)
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expectedPos int
+		expectedTok string
+	}{
+		{"Unterminated quote", `hello "world`, 6, `"world`},
+		{"Unknown operator", "hello to:bob", 6, "to:bob"},
+		{"Missing value", "from:", 0, "from:"},
+		{"Bad date", "since:yesterday", 0, "since:yesterday"},
+		{"Unsupported has", "x has:video", 2, "has:video"},
+		{"Negated operator", "-from:bob", 0, "-from:bob"},
+		{"Dangling minus", "hello -", 6, "-"},
+		{"Empty phrase", `""`, 0, `""`},
+	}
+
+	for _, tt := range tests {
+		_, err := search.Parse(tt.input)
+		var syntaxErr *search.SyntaxError
+		require.ErrorAs(t, err, &syntaxErr, tt.name)
+		assert.Equal(t, tt.expectedPos, syntaxErr.Pos, tt.name)
+		assert.Equal(t, tt.expectedTok, syntaxErr.Token, tt.name)
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	_, err := search.Parse("   ")
+	require.ErrorIs(t, err, search.ErrEmptyQuery)
+}
+
+func TestParse_UntilBeforeSince(t *testing.T) {
+	_, err := search.Parse("since:2026-02-01 until:2026-01-01")
+	var syntaxErr *search.SyntaxError
+	require.ErrorAs(t, err, &syntaxErr)
+}
+
+func TestCompile(t *testing.T) {
+	authorID := uuid.New()
+	query, err := search.Parse("gopher -spam from:" + authorID.String() + " since:2026-01-01 until:2026-01-31")
+	require.NoError(t, err)
+
+	compiled := query.Compile()
+
+	assert.Equal(t, []interface{}{
+		"gopher",
+		"spam",
+		authorID,
+		time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+	}, compiled.Args)
+	assert.Contains(t, compiled.Where, "to_tsvector('english', body) @@ (plainto_tsquery('english', $1))")
+	assert.Contains(t, compiled.Where, "NOT to_tsvector('english', body) @@ phraseto_tsquery('english', $2)")
+	assert.Contains(t, compiled.Where, "user_id = $3")
+	assert.Contains(t, compiled.Where, "created_at >= $4")
+	assert.Contains(t, compiled.Where, "created_at < $5")
+	assert.Contains(t, compiled.Rank, "ts_rank")
+	assert.Contains(t, compiled.Snippet, "ts_headline('english', replace(")
+	assert.NotContains(t, compiled.Where, "gopher")
+}
+
+func TestCompile_ExcludedStopword(t *testing.T) {
+	query, err := search.Parse("cats -the")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"the"}, query.Excluded)
+
+	compiled := query.Compile()
+
+	assert.Equal(t, []interface{}{"cats", "the"}, compiled.Args)
+	assert.Contains(t, compiled.Where, "(numnode(phraseto_tsquery('english', $2)) = 0 OR NOT ")
+}
+
+func TestCompile_FiltersOnly(t *testing.T) {
+	query, err := search.Parse("has:link from:bob@example.com")
+	require.NoError(t, err)
+
+	compiled := query.Compile()
+
+	assert.Equal(t, "0", compiled.Rank)
+	assert.Contains(t, compiled.Snippet, "'<', '&lt;'")
+	assert.NotContains(t, compiled.Where, "email")
+	assert.Contains(t, compiled.Where, "lower(username) = lower($1)")
+	assert.Equal(t, []interface{}{"bob@example.com"}, compiled.Args)
+}
+
+func TestCompile_FromUsername(t *testing.T) {
+	query, err := search.Parse("from:Bob")
+	require.NoError(t, err)
+
+	compiled := query.Compile()
+
+	assert.Contains(t, compiled.Where, "lower(username) = lower($1)")
+	assert.Equal(t, []interface{}{"Bob"}, compiled.Args)
+}
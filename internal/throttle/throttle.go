@@ -0,0 +1,70 @@
+// Package throttle decides how long to make a client wait after repeated
+// failures, such as wrong passwords. It only holds the policy; callers
+// store the failure counts wherever suits them.
+package throttle
+
+import (
+	"time"
+)
+
+// Policy is an exponential backoff with a hard lockout. After FreeAttempts
+// failures each further failure must be followed by a wait, starting at
+// BaseDelay and doubling up to MaxDelay. At LockoutThreshold failures the
+// key is locked until Lockout has passed since the last failure. Failures
+// are forgotten once Lockout has passed since the last one.
+type Policy struct {
+	FreeAttempts     int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	LockoutThreshold int
+	Lockout          time.Duration
+}
+
+// State is what is remembered about one key.
+type State struct {
+	Failures    int
+	LastFailure time.Time
+}
+
+// Active returns the failure count that still counts at now.
+func (p Policy) Active(s State, now time.Time) int {
+	if s.Failures == 0 || !now.Before(s.LastFailure.Add(p.Lockout)) {
+		return 0
+	}
+	return s.Failures
+}
+
+// RetryAfter returns how long the key must wait before its next attempt,
+// or zero if it may try now.
+func (p Policy) RetryAfter(s State, now time.Time) time.Duration {
+	failures := p.Active(s, now)
+	var wait time.Duration
+	switch {
+	case p.LockoutThreshold > 0 && failures >= p.LockoutThreshold:
+		wait = p.Lockout
+	case failures > p.FreeAttempts:
+		wait = p.delay(failures - p.FreeAttempts)
+	default:
+		return 0
+	}
+
+	remaining := s.LastFailure.Add(wait).Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Locked reports whether the key has reached the lockout threshold.
+func (p Policy) Locked(s State, now time.Time) bool {
+	return p.LockoutThreshold > 0 && p.Active(s, now) >= p.LockoutThreshold
+}
+
+// delay is the backoff after the nth failure past the free attempts.
+func (p Policy) delay(n int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < n && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
@@ -0,0 +1,77 @@
+package throttle_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Myles-J/chirpy/internal/throttle"
+)
+
+func testPolicy() throttle.Policy {
+	return throttle.Policy{
+		FreeAttempts:     3,
+		BaseDelay:        time.Second,
+		MaxDelay:         10 * time.Second,
+		LockoutThreshold: 10,
+		Lockout:          15 * time.Minute,
+	}
+}
+
+func TestRetryAfterBacksOffExponentially(t *testing.T) {
+	policy := testPolicy()
+	last := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{6, 4 * time.Second},
+		{7, 8 * time.Second},
+		{8, 10 * time.Second},
+		{9, 10 * time.Second},
+		{10, 15 * time.Minute},
+	}
+	for _, tt := range tests {
+		got := policy.RetryAfter(throttle.State{Failures: tt.failures, LastFailure: last}, last)
+		assert.Equal(t, tt.want, got, "%d failures", tt.failures)
+	}
+}
+
+func TestRetryAfterCountsFromLastFailure(t *testing.T) {
+	policy := testPolicy()
+	last := time.Unix(1_700_000_000, 0)
+	state := throttle.State{Failures: 5, LastFailure: last}
+
+	assert.Equal(t, 1500*time.Millisecond, policy.RetryAfter(state, last.Add(500*time.Millisecond)))
+	assert.Zero(t, policy.RetryAfter(state, last.Add(2*time.Second)))
+}
+
+func TestLockoutExpires(t *testing.T) {
+	policy := testPolicy()
+	last := time.Unix(1_700_000_000, 0)
+	state := throttle.State{Failures: 12, LastFailure: last}
+
+	assert.True(t, policy.Locked(state, last.Add(time.Minute)))
+	assert.Equal(t, 14*time.Minute, policy.RetryAfter(state, last.Add(time.Minute)))
+
+	later := last.Add(policy.Lockout)
+	assert.False(t, policy.Locked(state, later))
+	assert.Zero(t, policy.RetryAfter(state, later))
+	assert.Zero(t, policy.Active(state, later), "failures should be forgotten after the lockout")
+}
+
+func TestNoLockoutThreshold(t *testing.T) {
+	policy := testPolicy()
+	policy.LockoutThreshold = 0
+	last := time.Unix(1_700_000_000, 0)
+	state := throttle.State{Failures: 100, LastFailure: last}
+
+	assert.False(t, policy.Locked(state, last))
+	assert.Equal(t, policy.MaxDelay, policy.RetryAfter(state, last))
+}
@@ -0,0 +1,92 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Myles-J/chirpy/internal/totp"
+)
+
+// rfcSecret is the SHA-1 seed from RFC 6238 appendix B, base32 encoded.
+func rfcSecret() string {
+	return base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+}
+
+func TestCodeMatchesRFC6238(t *testing.T) {
+	// RFC 6238 appendix B lists eight digit codes; six digit codes are
+	// their last six digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		code, err := totp.Code(rfcSecret(), totp.Step(time.Unix(tt.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, code, "T=%d", tt.unix)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := totp.Step(now)
+
+	previous, err := totp.Code(rfcSecret(), step-1)
+	require.NoError(t, err)
+	tooOld, err := totp.Code(rfcSecret(), step-2)
+	require.NoError(t, err)
+
+	got, err := totp.Verify(rfcSecret(), "050471", now)
+	require.NoError(t, err)
+	assert.Equal(t, step, got)
+
+	got, err = totp.Verify(rfcSecret(), previous, now)
+	require.NoError(t, err, "codes from one step ago should be accepted")
+	assert.Equal(t, step-1, got)
+
+	_, err = totp.Verify(rfcSecret(), tooOld, now)
+	require.ErrorIs(t, err, totp.ErrInvalidCode)
+
+	_, err = totp.Verify(rfcSecret(), "12345", now)
+	require.ErrorIs(t, err, totp.ErrInvalidCode)
+
+	_, err = totp.Verify("not base32!", "050471", now)
+	require.ErrorIs(t, err, totp.ErrInvalidSecret)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32, "160 bits is 32 base32 characters")
+
+	other, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, other)
+
+	_, err = totp.Code(secret, 1)
+	require.NoError(t, err)
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(totp.URI("JBSWY3DPEHPK3PXP", "Chirpy", "alice@example.com"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Chirpy:alice@example.com", uri.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+	assert.Equal(t, "Chirpy", uri.Query().Get("issuer"))
+	assert.Equal(t, "6", uri.Query().Get("digits"))
+	assert.Equal(t, "30", uri.Query().Get("period"))
+}
@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/Myles-J/chirpy/internal/logger"
 )
@@ -15,3 +18,40 @@ func MustGetenv(key string) string {
 	}
 	return val
 }
+
+// GetenvDefault returns the value of the environment variable key, or
+// fallback if it is unset or empty.
+func GetenvDefault(key, fallback string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return fallback
+}
+
+// GetenvInt parses the environment variable key as an integer, returning
+// fallback if it is unset or empty.
+func GetenvInt(key string, fallback int) (int, error) {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// GetenvDuration parses the environment variable key as a duration such as
+// "15m", returning fallback if it is unset or empty.
+func GetenvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d, nil
+}
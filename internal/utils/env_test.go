@@ -2,6 +2,7 @@ package utils_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/Myles-J/chirpy/internal/utils"
 )
@@ -16,3 +17,53 @@ func TestMustGetenv(t *testing.T) {
 		t.Errorf("got %q, want %q", got, want)
 	}
 }
+
+func TestGetenvDefault(t *testing.T) {
+	t.Setenv("TEST_ENV", "test")
+	t.Setenv("TEST_ENV_EMPTY", "")
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"TEST_ENV", "test"},
+		{"TEST_ENV_EMPTY", "fallback"},
+		{"TEST_ENV_UNSET", "fallback"},
+	}
+
+	for _, tt := range tests {
+		if got := utils.GetenvDefault(tt.key, "fallback"); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestGetenvInt(t *testing.T) {
+	t.Setenv("TEST_ENV_INT", "42")
+	t.Setenv("TEST_ENV_BAD", "forty-two")
+
+	if got, err := utils.GetenvInt("TEST_ENV_INT", 7); err != nil || got != 42 {
+		t.Errorf("got %d, %v, want 42", got, err)
+	}
+	if got, err := utils.GetenvInt("TEST_ENV_UNSET", 7); err != nil || got != 7 {
+		t.Errorf("got %d, %v, want fallback 7", got, err)
+	}
+	if _, err := utils.GetenvInt("TEST_ENV_BAD", 7); err == nil {
+		t.Error("expected an error for a non-numeric value")
+	}
+}
+
+func TestGetenvDuration(t *testing.T) {
+	t.Setenv("TEST_ENV_DURATION", "90s")
+	t.Setenv("TEST_ENV_BAD", "soon")
+
+	if got, err := utils.GetenvDuration("TEST_ENV_DURATION", time.Minute); err != nil || got != 90*time.Second {
+		t.Errorf("got %v, %v, want 1m30s", got, err)
+	}
+	if got, err := utils.GetenvDuration("TEST_ENV_UNSET", time.Minute); err != nil || got != time.Minute {
+		t.Errorf("got %v, %v, want fallback 1m0s", got, err)
+	}
+	if _, err := utils.GetenvDuration("TEST_ENV_BAD", time.Minute); err == nil {
+		t.Error("expected an error for an invalid duration")
+	}
+}
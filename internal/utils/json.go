@@ -28,6 +28,21 @@ func RespondWithError(w http.ResponseWriter, code int, message string, err error
 	})
 }
 
+// RespondWithErrorDetails responds like RespondWithError, with details
+// describing what was wrong, such as every validation rule a field broke,
+// alongside the message.
+func RespondWithErrorDetails(w http.ResponseWriter, code int, message string, details any) {
+	type errorResponse struct {
+		Error   string `json:"error"`
+		Details any    `json:"details"`
+	}
+
+	RespondWithJSON(w, code, errorResponse{
+		Error:   message,
+		Details: details,
+	})
+}
+
 // RespondWithJSON responds to the client with a JSON payload.
 // It sets the Content-Type header to application/json, marshals the payload to JSON,
 // and writes the JSON data to the response writer.
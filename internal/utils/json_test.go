@@ -52,3 +52,20 @@ func TestRespondWithError(t *testing.T) {
 	require.NoError(t, e)
 	assert.Equal(t, msg, resp["error"])
 }
+
+func TestRespondWithErrorDetails(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	details := []testPayload{{Message: "too short"}, {Message: "too common"}}
+	utils.RespondWithErrorDetails(recorder, http.StatusBadRequest, "invalid password", details)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var resp struct {
+		Error   string        `json:"error"`
+		Details []testPayload `json:"details"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.Equal(t, "invalid password", resp.Error)
+	assert.Equal(t, details, resp.Details)
+}